@@ -0,0 +1,126 @@
+package gotau
+
+import (
+	"fmt"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+)
+
+// DefaultBreathAliases are the aliases of breath samples commonly found in UTAU voicebanks.
+var DefaultBreathAliases = []string{"br", "吸", "息", "breath", "- br"}
+
+// DefaultBreath is a [Breath] with moderate settings.
+var DefaultBreath = Breath{
+	MinRest: 300,
+	Length:  250,
+	Volume:  0.6,
+}
+
+// Breath configures the automatic insertion of breaths at the starts of phrases.
+type Breath struct {
+	// MinRest is the shortest rest in milliseconds before a phrase that gets a breath.
+	MinRest float64
+
+	// Length is the length of the breath in milliseconds. Breaths are shortened to fit into the rest.
+	Length float64
+
+	// Volume is the volume of the breath (0.0 to 1.0), like [sequence.Note.Intensity].
+	Volume float64
+
+	// Aliases are the oto aliases of the breath samples. The first one found in the voicebank is used.
+	// If it's empty, [DefaultBreathAliases] is used.
+	Aliases []string
+}
+
+// SetBreath enables the automatic insertion of breaths. If b is nil (the default), no breaths are inserted.
+//
+// A phrase starts at a note that follows a rest of at least [Breath.MinRest] (including the start of the song).
+// The breath sample ends where the sample of that note starts, crossfaded by the note's overlap.
+// If the voicebank has no breath sample, no breaths are inserted.
+func (s *Synth) SetBreath(b *Breath) {
+	s.breath = b
+}
+
+// planNotes plans the note and the breath before it (if any), in the order they are concatenated.
+// Like planNote, it must be called in order.
+func (s *Synth) planNotes(note sequence.Note) []*renderJob {
+	restStart := s.phraseEnd
+	s.phraseEnd = max(s.phraseEnd, note.Position+note.Duration)
+
+	job := s.planNote(note)
+	if breath := s.planBreath(note, job, restStart); breath != nil {
+		return []*renderJob{breath, job}
+	}
+	return []*renderJob{job}
+}
+
+// planBreath plans a breath before the note (planned as job) if the note starts a phrase after a rest from restStart.
+func (s *Synth) planBreath(note sequence.Note, job *renderJob, restStart int) *renderJob {
+	if s.breath == nil || job.silent || job.err != nil {
+		return nil
+	}
+
+	restStartMs := s.ticksToMs(restStart)
+	if s.ticksToMs(note.Position)-restStartMs < s.breath.MinRest {
+		return nil
+	}
+
+	otoEntry, ok := s.getBreathOtoEntry()
+	if !ok {
+		return nil
+	}
+
+	endMs := s.samplesToMs(job.start) + job.overlap
+	startMs := max(endMs-s.breath.Length, restStartMs)
+	length := endMs - startMs
+	if length <= 0 || !s.inRange(startMs, endMs) {
+		return nil
+	}
+
+	// the breath belongs to the note, but it's silent at the note's pitch and has no expression of its own
+	breathNote := sequence.Note{
+		Position:  note.Position,
+		Lyric:     otoEntry.Alias,
+		Note:      note.Note,
+		Intensity: s.breath.Volume,
+	}
+	breath := &renderJob{
+		note:     breathNote,
+		otoEntry: otoEntry,
+		start:    s.msToSamples(startMs),
+		breath:   true,
+		done:     make(chan struct{}),
+	}
+	breath.resampleCfg = s.getResampleConfig(otoEntry, breathNote, length)
+	breath.concatCfg = concat.Config{
+		Length:      length,
+		Envelope:    sequence.DefaultEnvelope,
+		Intensity:   s.breath.Volume,
+		AudioFormat: breath.resampleCfg.AudioFormat,
+	}
+
+	s.logger.Debug("inserted breath", "tick", note.Position, "alias", otoEntry.Alias, "length", length)
+
+	if err := s.beforeResample(breath); err != nil {
+		breath.err = fmt.Errorf("failed to run hook: %w", err)
+		close(breath.done)
+		return breath
+	}
+	breath.length = s.msToSamples(breath.concatCfg.Offset + breath.concatCfg.Length)
+	return breath
+}
+
+func (s *Synth) getBreathOtoEntry() (voicebank.OtoEntry, bool) {
+	aliases := s.breath.Aliases
+	if len(aliases) == 0 {
+		aliases = DefaultBreathAliases
+	}
+	for _, alias := range aliases {
+		if e, ok := s.vb.Oto.Get(alias); ok {
+			return e, true
+		}
+	}
+	return voicebank.OtoEntry{}, false
+}
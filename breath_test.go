@@ -0,0 +1,70 @@
+package gotau_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+var breathTestSeq = sequence.Sequence{
+	Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+	Notes: []sequence.Note{
+		{Position: 0, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+		{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},  // legato, no breath
+		{Position: 1920, Duration: 480, Lyric: "a", Note: 62, Intensity: 1}, // after a 1 s rest
+	},
+}
+
+func TestSynth_SetBreath(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a", Preutterance: 100, Overlap: 20},
+			{Filename: "br.wav", Alias: "br"},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+	synth.SetBreath(&gotau.Breath{MinRest: 500, Length: 250, Volume: 0.5})
+
+	plan := synth.Plan(breathTestSeq)
+	assert.Len(t, plan.Notes, 4)
+
+	br := plan.Notes[2]
+	assert.True(t, br.Breath)
+	assert.Equal(t, "br", br.Alias)
+	assert.Equal(t, 1920, br.Position)
+	assert.Equal(t, 1670, br.StartSample) // the note's sample starts at 1900 ms, the overlap adds 20 ms
+	assert.Equal(t, 1920, br.EndSample)
+	assert.Equal(t, 0.5, br.ConcatConfig.Intensity)
+	assert.EqualValues(t, 62, br.ResampleConfig.Pitch)
+
+	assert.False(t, plan.Notes[3].Breath)
+	assert.Equal(t, 1900, plan.Notes[3].StartSample)
+}
+
+func TestSynth_SetBreath_ShortRest(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a", Preutterance: 100, Overlap: 20},
+			{Filename: "br.wav", Alias: "br"},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+	synth.SetBreath(&gotau.Breath{MinRest: 1500, Length: 250, Volume: 0.5})
+
+	assert.Len(t, synth.Plan(breathTestSeq).Notes, 3)
+}
+
+func TestSynth_SetBreath_NoBreathSample(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a", Preutterance: 100, Overlap: 20},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+	synth.SetBreath(&gotau.DefaultBreath)
+
+	assert.Len(t, synth.Plan(breathTestSeq).Notes, 3)
+}
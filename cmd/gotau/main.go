@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SladkyCitron/enczip/zip"
 	"github.com/SladkyCitron/gotau"
 	"github.com/SladkyCitron/resona/afmt"
-	"github.com/SladkyCitron/resona/aio"
-	"github.com/SladkyCitron/resona/codec/wav"
-	"github.com/SladkyCitron/resona/freq"
 
 	"github.com/SladkyCitron/gotau/cache/diskcache"
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/effect"
 	"github.com/SladkyCitron/gotau/phonemizer"
+	"github.com/SladkyCitron/gotau/pitch"
 	"github.com/SladkyCitron/gotau/resample/external"
 	"github.com/SladkyCitron/gotau/sequence/ust"
 	"github.com/SladkyCitron/gotau/voicebank"
@@ -24,16 +30,35 @@ import (
 )
 
 func main() {
-	if len(os.Args) != 4 {
-		fmt.Fprintf(os.Stderr, "Usage: %s voicebank.zip song.ust output.wav", os.Args[0])
+	fx := flag.String("fx", "", `effect chain, e.g. "eq:lowcut=80,high=2;compressor:threshold=-20;reverb:mix=0.15"`)
+	var loudnessTarget, truePeakLimit *float64
+	flag.Func("loudness", "normalize the loudness to the target in LUFS, e.g. -14 (default: not normalized)", optionalFloat(&loudnessTarget))
+	flag.Func("limit", "limit the true peaks to the ceiling in dBTP, e.g. -1 (default: no limiter)", optionalFloat(&truePeakLimit))
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-fx chain] [-loudness LUFS] [-limit dBTP] voicebank.zip song.ust output.wav\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) != 3 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
+	effectCfgs, err := effect.ParseChain(*fx)
+	if err != nil {
+		panic(err)
+	}
+	effects, err := effect.NewChain(effectCfgs)
+	if err != nil {
+		panic(err)
+	}
+
 	before := time.Now()
 
 	println("loading voicebank")
 
-	zr, err := zip.OpenReader(os.Args[1], encoding.Nop)
+	zr, err := zip.OpenReader(args[0], encoding.Nop)
 	if err != nil {
 		panic(err)
 	}
@@ -50,7 +75,7 @@ func main() {
 
 	println("loading UST")
 
-	inFile, err := os.Open(os.Args[2])
+	inFile, err := os.Open(args[1])
 	if err != nil {
 		panic(err)
 	}
@@ -74,35 +99,41 @@ func main() {
 		//cmd.Stdout = os.Stdout
 		//cmd.Stderr = os.Stderr
 	}
-	synth := gotau.New(44100, vb, res, nil)
-	synth.SetPhonemizer(&phonemizer.CV{PrefixMap: vb.PrefixMap})
 	cacheDir, _ := diskcache.Dir(gotau.ResamplerDiskCacheDir)
-	synth.SetResamplerCache(diskcache.New(cacheDir, gotau.ResamplerDiskCacheExt))
-	synth.EnqueueSequence(seq)
 
-	outFile, err := os.Create(os.Args[3])
-	if err != nil {
-		panic(err)
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	enc, err := wav.NewEncoder(outFile, afmt.Format{44100 * freq.Hertz, 1}, afmt.SampleFormat{16, afmt.SampleEncodingInt, binary.LittleEndian}, wav.FormatInt)
+	outFile, err := os.Create(args[2])
 	if err != nil {
 		panic(err)
 	}
 
 	println("rendering")
-	if _, err := aio.Copy(enc, synth); err != nil {
-		panic(err)
-	}
-
-	if err := enc.Close(); err != nil {
+	stats, err := gotau.RenderToWriter(outFile, seq, vb, gotau.RenderOptions{
+		Resampler:      res,
+		Concatenator:   &concat.Wavtool{},
+		Phonemizer:     &phonemizer.CV{PrefixMap: vb.PrefixMap},
+		AutoPitch:      &pitch.DefaultAutoPitch,
+		ResamplerCache: diskcache.New(cacheDir, gotau.ResamplerDiskCacheExt),
+		SampleRate:     44100,
+		Effects:        effects,
+		Loudness:       loudnessTarget,
+		TruePeakLimit:  truePeakLimit,
+		Concurrency:    runtime.NumCPU(), // the external resampler and the disk cache are safe for concurrent use
+		Context:        ctx,
+		ProgressFunc:   printProgress,
+	})
+	if err != nil {
 		panic(err)
 	}
+	fmt.Fprintln(os.Stderr)
 
 	if err := outFile.Close(); err != nil {
 		panic(err)
 	}
 
+	fmt.Printf("Rendered %s of audio\n", stats.Duration.Round(time.Millisecond))
 	fmt.Printf("Done!\nTook %s\n", time.Since(before).String())
 
 	// not getting rid of this, I'm very proud of this one :)
@@ -129,3 +160,32 @@ func main() {
 		}
 	*/
 }
+
+// optionalFloat returns a flag function that parses the value into a new float pointed to by *p.
+func optionalFloat(p **float64) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*p = &v
+		return nil
+	}
+}
+
+func printProgress(p gotau.Progress) {
+	const width = 30
+	filled := int(p.Percent() / 100 * width)
+	fmt.Fprintf(
+		os.Stderr,
+		"\r[%s%s] %5.1f%% %d/%d notes (%d cached) elapsed %s ETA %s   ",
+		strings.Repeat("#", filled),
+		strings.Repeat("-", width-filled),
+		p.Percent(),
+		p.NotesRendered,
+		p.NotesTotal,
+		p.CacheHits,
+		p.Elapsed.Round(time.Second),
+		p.ETA().Round(time.Second),
+	)
+}
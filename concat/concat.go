@@ -1,5 +1,44 @@
+// Package concat implements the concatenation of rendered notes into the final waveform
+// (i.e. what a wavtool does in UTAU) and provides multiple ready-to-use implementations.
 package concat
 
+import (
+	"context"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/afmt"
+)
+
+// Concatenator joins resampled notes into the output waveform.
 type Concatenator interface {
-	Concatenate()
+	// Concatenate mixes the resampled note in into the end of dst according to the
+	// provided concatenation configuration (offset, length, envelope, overlap, etc.)
+	// and returns the extended slice, similar to append.
+	//
+	// dst holds the tail of the output waveform that has not been consumed yet.
+	// Only the last [Config.Overlap] milliseconds of dst may be modified.
+	// It should stop and return ctx.Err() when ctx is canceled.
+	Concatenate(ctx context.Context, dst []float32, in []float32, cfg Config) ([]float32, error)
+}
+
+// Config represents the configuration for passing into [Concatenator.Concatenate].
+type Config struct {
+	// Offset is the time in milliseconds to skip at the start of the resampled note (i.e. start point).
+	Offset float64
+
+	// Length is the length of the resampled note to use in milliseconds (after Offset).
+	Length float64
+
+	// Envelope is the volume envelope applied to the note.
+	Envelope sequence.Envelope
+
+	// Overlap is the time in milliseconds that the note overlaps the end of the output.
+	// Negative values insert a gap instead.
+	Overlap float64
+
+	// Intensity is the volume of the note. It is a value between 0 and 2, where 1 is the original volume.
+	Intensity float64
+
+	// AudioFormat is the audio format of the input and output audio data.
+	AudioFormat afmt.Format
 }
@@ -0,0 +1,168 @@
+// Package external implements an external concatenator.
+package external
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/SladkyCitron/resona/codec/wav"
+)
+
+var _ concat.Concatenator = (*Concatenator)(nil)
+
+// Concatenator is a concatenator that uses an external command-line UTAU wavtool program to perform concatenation.
+//
+// Since wavtools append to their output file, the Concatenator writes the part of the
+// output that the note overlaps into a temporary output file, lets the wavtool append the
+// note to it and reads the result back. The wavtool must write a regular WAV file
+// (like wavtool2 and wavtool-yawu do) in [concat.Config.AudioFormat]; the .whd / .dat output
+// of the classic wavtool is not supported.
+type Concatenator struct {
+	// ConfigureCmd is an optional hook that allows configuring the exec.Cmd before running it.
+	ConfigureCmd func(cmd *exec.Cmd)
+
+	cmdName   string
+	sampleFmt afmt.SampleFormat
+}
+
+// New creates a new [Concatenator] with the given program name and
+// sample format for encoding temporary WAV files for passing into the wavtool.
+//
+// The program should be a command-line UTAU wavtool (e.g. wavtool2, wavtool-yawu, etc.)
+// that accepts the following arguments:
+//
+//	wavtool <outfile> <infile> offset length p1 p2 p3 v1 v2 v3 v4 ovr p4 p5 v5
+func New(name string, sampleFmt afmt.SampleFormat) *Concatenator {
+	return &Concatenator{cmdName: name, sampleFmt: sampleFmt}
+}
+
+func (c *Concatenator) Concatenate(ctx context.Context, dst []float32, in []float32, cfg concat.Config) ([]float32, error) {
+	// the wavtool only touches the overlapping part of the output
+	sr := cfg.AudioFormat.SampleRate.Hertz()
+	ovr := min(max(int(math.Round(cfg.Overlap*sr/1000)), 0), len(dst))
+	head, tail := dst[:len(dst)-ovr], dst[len(dst)-ovr:]
+
+	output, err := c.createTempWav("gotau-externalwavtool-out-*.wav", tail, 1, cfg)
+	if err != nil {
+		return dst, fmt.Errorf("external: failed to create temporary output wav file: %w", err)
+	}
+	defer func() { _ = os.Remove(output) }() // clean up
+
+	// wavtools don't know about intensity, so apply it beforehand
+	input, err := c.createTempWav("gotau-externalwavtool-in-*.wav", in, float32(cfg.Intensity), cfg)
+	if err != nil {
+		return dst, fmt.Errorf("external: failed to create temporary input wav file: %w", err)
+	}
+	defer func() { _ = os.Remove(input) }() // clean up
+
+	env := cfg.Envelope
+	cmd := exec.CommandContext(
+		ctx,
+		c.cmdName,
+		output,
+		input,
+		strconv.FormatFloat(cfg.Offset, 'f', -1, 64),
+		"0@120+"+strconv.FormatFloat(cfg.Length, 'f', -1, 64), // UTAU-style length (ticks@tempo+ms) works with every wavtool
+		strconv.FormatFloat(env.P1, 'f', -1, 64),
+		strconv.FormatFloat(env.P2, 'f', -1, 64),
+		strconv.FormatFloat(env.P3, 'f', -1, 64),
+		strconv.FormatFloat(env.V1, 'f', -1, 64),
+		strconv.FormatFloat(env.V2, 'f', -1, 64),
+		strconv.FormatFloat(env.V3, 'f', -1, 64),
+		strconv.FormatFloat(env.V4, 'f', -1, 64),
+		strconv.FormatFloat(cfg.Overlap, 'f', -1, 64),
+		strconv.FormatFloat(env.P4, 'f', -1, 64),
+		strconv.FormatFloat(env.P5, 'f', -1, 64),
+		strconv.FormatFloat(env.V5, 'f', -1, 64),
+	)
+	if c.ConfigureCmd != nil {
+		c.ConfigureCmd(cmd)
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return dst, ctx.Err()
+		}
+		return dst, fmt.Errorf("external: failed to run wavtool command: %w", err)
+	}
+
+	out, err := c.decodeOutFile(output, cfg.AudioFormat)
+	if err != nil {
+		return dst, fmt.Errorf("external: failed to decode output wav file: %w", err)
+	}
+
+	return append(head, out...), nil
+}
+
+func (c *Concatenator) createTempWav(pattern string, samples []float32, gain float32, cfg concat.Config) (_ string, err error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	var wavFormat uint16
+	switch c.sampleFmt.Encoding {
+	case afmt.SampleEncodingInt, afmt.SampleEncodingUint:
+		wavFormat = wav.FormatInt
+	case afmt.SampleEncodingFloat:
+		wavFormat = wav.FormatFloat
+	default:
+		return "", fmt.Errorf("invalid sample format: %s", c.sampleFmt.String())
+	}
+	enc, err := wav.NewEncoder(f, cfg.AudioFormat, c.sampleFmt, wavFormat)
+	if err != nil {
+		return "", err
+	}
+
+	if gain != 1 {
+		scaled := make([]float32, len(samples))
+		for i, v := range samples {
+			scaled[i] = v * gain
+		}
+		samples = scaled
+	}
+
+	if _, err := aio.Copy(enc, dsp.NewSliceReader(samples)); err != nil {
+		return "", err
+	}
+
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+func (c *Concatenator) decodeOutFile(path string, format afmt.Format) ([]float32, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	deco, err := wav.NewDecoder(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// the output is mixed into the synth's output as is, so it can't be converted here
+	if got := deco.Format(); got.SampleRate != format.SampleRate || got.NumChannels != format.NumChannels {
+		return nil, fmt.Errorf("unexpected audio format: got %v Hz with %d channels, want %v Hz with %d channels",
+			got.SampleRate.Hertz(), got.NumChannels, format.SampleRate.Hertz(), format.NumChannels)
+	}
+
+	return dsp.ReadAll(deco)
+}
@@ -0,0 +1,157 @@
+package external_test
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/concat/external"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/codec/wav"
+	"github.com/SladkyCitron/resona/freq"
+	"github.com/stretchr/testify/assert"
+)
+
+var float32Fmt = afmt.SampleFormat{BitDepth: 32, Encoding: afmt.SampleEncodingFloat, Endian: binary.LittleEndian}
+
+// TestMain runs the test binary as a fake wavtool when GOTAU_FAKE_WAVTOOL is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("GOTAU_FAKE_WAVTOOL") != "" {
+		if err := fakeWavtool(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeWavtool records its arguments into the file in GOTAU_FAKE_WAVTOOL and rewrites the output file
+// with its samples plus 10, followed by the input samples. If GOTAU_FAKE_WAVTOOL_RATE is set,
+// the output file is written with that sample rate.
+func fakeWavtool(args []string) error {
+	if err := os.WriteFile(os.Getenv("GOTAU_FAKE_WAVTOOL"), []byte(strings.Join(args, "\n")), 0o644); err != nil {
+		return err
+	}
+
+	out, format, err := readWav(args[0])
+	if err != nil {
+		return err
+	}
+	in, _, err := readWav(args[1])
+	if err != nil {
+		return err
+	}
+	for i := range out {
+		out[i] += 10
+	}
+	out = append(out, in...)
+
+	if rate := os.Getenv("GOTAU_FAKE_WAVTOOL_RATE"); rate != "" {
+		sr, err := strconv.Atoi(rate)
+		if err != nil {
+			return err
+		}
+		format.SampleRate = freq.Frequency(sr) * freq.Hertz
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc, err := wav.NewEncoder(f, format, float32Fmt, wav.FormatFloat)
+	if err != nil {
+		return err
+	}
+	if _, err := enc.WriteSamples(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func readWav(path string) ([]float32, afmt.Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, afmt.Format{}, err
+	}
+	defer f.Close()
+	deco, err := wav.NewDecoder(f)
+	if err != nil {
+		return nil, afmt.Format{}, err
+	}
+	samples, err := dsp.ReadAll(deco)
+	return samples, deco.Format(), err
+}
+
+// newFakeWavtool returns a Concatenator that runs the fake wavtool and the path of the file with its arguments.
+func newFakeWavtool(t *testing.T, env ...string) (*external.Concatenator, string) {
+	argsPath := filepath.Join(t.TempDir(), "args")
+	c := external.New(os.Args[0], float32Fmt)
+	c.ConfigureCmd = func(cmd *exec.Cmd) {
+		cmd.Env = append(os.Environ(), "GOTAU_FAKE_WAVTOOL="+argsPath)
+		cmd.Env = append(cmd.Env, env...)
+	}
+	return c, argsPath
+}
+
+func TestConcatenator_Concatenate(t *testing.T) {
+	c, argsPath := newFakeWavtool(t)
+
+	dst := []float32{1, 2, 3, 4, 5, 6}
+	in := []float32{0.5, 0.25, 0.125}
+	out, err := c.Concatenate(context.Background(), dst, in, concat.Config{
+		Offset:      10,
+		Length:      20.5,
+		Envelope:    sequence.DefaultEnvelope,
+		Overlap:     2, // 2 samples at 1 kHz
+		Intensity:   0.5,
+		AudioFormat: afmt.Format{SampleRate: 1000 * freq.Hertz, NumChannels: 1},
+	})
+	assert.NoError(t, err)
+
+	// only the overlapped tail goes through the wavtool, and the input is scaled by the intensity
+	assert.Equal(t, []float32{1, 2, 3, 4, 15, 16, 0.25, 0.125, 0.0625}, out)
+
+	b, err := os.ReadFile(argsPath)
+	assert.NoError(t, err)
+	args := strings.Split(string(b), "\n")
+	if assert.Len(t, args, 15) {
+		assert.Equal(t, []string{"10", "0@120+20.5", "0", "5", "35", "0", "100", "100", "0", "2", "0", "0", "100"}, args[2:])
+	}
+}
+
+func TestConcatenator_Concatenate_NegativeOverlap(t *testing.T) {
+	c, _ := newFakeWavtool(t)
+
+	out, err := c.Concatenate(context.Background(), []float32{1, 2}, []float32{0.5}, concat.Config{
+		Envelope:    sequence.DefaultEnvelope,
+		Overlap:     -3, // the wavtool inserts the gap
+		Intensity:   1,
+		AudioFormat: afmt.Format{SampleRate: 1000 * freq.Hertz, NumChannels: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1, 2, 0.5}, out)
+}
+
+func TestConcatenator_Concatenate_FormatMismatch(t *testing.T) {
+	c, _ := newFakeWavtool(t, "GOTAU_FAKE_WAVTOOL_RATE=44100")
+
+	dst := []float32{1, 2}
+	out, err := c.Concatenate(context.Background(), dst, []float32{0.5}, concat.Config{
+		Envelope:    sequence.DefaultEnvelope,
+		Intensity:   1,
+		AudioFormat: afmt.Format{SampleRate: 1000 * freq.Hertz, NumChannels: 1},
+	})
+	assert.ErrorContains(t, err, "unexpected audio format")
+	assert.Equal(t, dst, out)
+}
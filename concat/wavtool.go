@@ -0,0 +1,60 @@
+package concat
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+var _ Concatenator = (*Wavtool)(nil)
+
+// Wavtool is a built-in [Concatenator] that reproduces the behavior of UTAU's wavtool.
+//
+// Each note is placed [Config.Overlap] milliseconds before the end of the output,
+// shaped by [Config.Envelope] and [Config.Intensity] and mixed (added) on top of
+// the overlapping region, which results in a crossfade between adjacent notes.
+type Wavtool struct{}
+
+// Concatenate satisfies the [Concatenator] interface.
+func (w *Wavtool) Concatenate(ctx context.Context, dst []float32, in []float32, cfg Config) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return dst, err
+	}
+
+	sr := cfg.AudioFormat.SampleRate.Hertz()
+	if sr <= 0 {
+		return dst, fmt.Errorf("concat: invalid sample rate: %v", sr)
+	}
+
+	skip := msToSamples(cfg.Offset, sr)
+	length := msToSamples(cfg.Length, sr)
+
+	// negative overlap means a gap
+	ovr := msToSamples(cfg.Overlap, sr)
+	if ovr < 0 {
+		dst = append(dst, make([]float32, -ovr)...)
+		ovr = 0
+	}
+	ovr = min(ovr, len(dst))
+
+	start := len(dst) - ovr
+	if grow := start + length - len(dst); grow > 0 {
+		dst = append(dst, make([]float32, grow)...)
+	}
+
+	for i := range length {
+		j := skip + i
+		if j < 0 || j >= len(in) {
+			continue
+		}
+		t := float64(i) * 1000 / sr
+		gain := cfg.Envelope.Gain(t, cfg.Length) * cfg.Intensity
+		dst[start+i] += in[j] * float32(gain)
+	}
+
+	return dst, nil
+}
+
+func msToSamples(ms, sr float64) int {
+	return int(math.Round(ms * sr / 1000))
+}
@@ -0,0 +1,81 @@
+package concat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/freq"
+	"github.com/stretchr/testify/assert"
+)
+
+func ones(n int) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = 1
+	}
+	return s
+}
+
+var flatEnvelope = sequence.Envelope{V1: 100, V2: 100, V3: 100, V4: 100, V5: 100}
+
+func TestWavtool_Concatenate(t *testing.T) {
+	w := &concat.Wavtool{}
+	format := afmt.Format{SampleRate: 1000 * freq.Hertz, NumChannels: 1}
+
+	// 10 samples of note, then another 10 overlapping by 4
+	dst, err := w.Concatenate(context.Background(), nil, ones(10), concat.Config{
+		Length:      10,
+		Envelope:    flatEnvelope,
+		Intensity:   1,
+		AudioFormat: format,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, dst, 10)
+
+	dst, err = w.Concatenate(context.Background(), dst, ones(12), concat.Config{
+		Offset:      2,
+		Length:      10,
+		Envelope:    flatEnvelope,
+		Overlap:     4,
+		Intensity:   0.5,
+		AudioFormat: format,
+	})
+	assert.NoError(t, err)
+
+	want := []float32{1, 1, 1, 1, 1, 1, 1.5, 1.5, 1.5, 1.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	assert.Equal(t, want, dst)
+}
+
+func TestWavtool_Concatenate_NegativeOverlap(t *testing.T) {
+	w := &concat.Wavtool{}
+	format := afmt.Format{SampleRate: 1000 * freq.Hertz, NumChannels: 1}
+
+	dst, err := w.Concatenate(context.Background(), []float32{1}, ones(2), concat.Config{
+		Length:      2,
+		Envelope:    flatEnvelope,
+		Overlap:     -2,
+		Intensity:   1,
+		AudioFormat: format,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1, 0, 0, 1, 1}, dst)
+}
+
+func TestWavtool_Concatenate_InvalidSampleRate(t *testing.T) {
+	w := &concat.Wavtool{}
+
+	_, err := w.Concatenate(context.Background(), nil, ones(2), concat.Config{Length: 2, Intensity: 1})
+	assert.Error(t, err)
+}
+
+func TestWavtool_Concatenate_Canceled(t *testing.T) {
+	w := &concat.Wavtool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := w.Concatenate(ctx, nil, ones(2), concat.Config{Length: 2, Intensity: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+}
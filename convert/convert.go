@@ -0,0 +1,156 @@
+// Package convert implements the conversion of audio data between sample rates and channel layouts,
+// e.g. for normalizing voicebank samples to the synthesizer's output format.
+package convert
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// sincZeroCrossings is the number of zero crossings of the sinc kernel on each side.
+// Higher values result in a steeper anti-aliasing filter but slower conversion.
+const sincZeroCrossings = 16
+
+// NeedsConversion reports whether audio data in the from format has to be converted to match the to format.
+func NeedsConversion(from, to afmt.Format) bool {
+	return from.SampleRate != to.SampleRate || from.NumChannels != to.NumChannels
+}
+
+// Reader reads all samples from r, converts them from the from format to the to format
+// and returns a reader over the converted samples. If no conversion is needed, r is returned as is.
+func Reader(r aio.SampleReader, from, to afmt.Format) (aio.SampleReader, error) {
+	if !NeedsConversion(from, to) {
+		return r, nil
+	}
+
+	in, err := dsp.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := Convert(in, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return dsp.NewSliceReader(out), nil
+}
+
+// Convert converts the interleaved samples from the from format to the to format.
+func Convert(in []float32, from, to afmt.Format) ([]float32, error) {
+	// convert the sample rate with as few channels as possible
+	if to.NumChannels < from.NumChannels {
+		var err error
+		in, err = Channels(in, from.NumChannels, to.NumChannels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	channels := min(from.NumChannels, to.NumChannels)
+	if from.SampleRate != to.SampleRate {
+		var err error
+		in, err = SampleRate(in, channels, from.SampleRate.Hertz(), to.SampleRate.Hertz())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if to.NumChannels > channels {
+		return Channels(in, channels, to.NumChannels)
+	}
+	return in, nil
+}
+
+// Channels converts the interleaved samples from one number of channels to another.
+//
+// Multiple channels are downmixed to mono by averaging them and mono is upmixed by
+// copying it into every channel. Other conversions are not supported.
+func Channels(in []float32, from, to int) ([]float32, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("convert: invalid number of channels: %d -> %d", from, to)
+	}
+
+	switch {
+	case from == to:
+		return in, nil
+	case to == 1:
+		out := make([]float32, len(in)/from)
+		for i := range out {
+			var sum float32
+			for _, v := range in[i*from : (i+1)*from] {
+				sum += v
+			}
+			out[i] = sum / float32(from)
+		}
+		return out, nil
+	case from == 1:
+		out := make([]float32, len(in)*to)
+		for i, v := range in {
+			for c := range to {
+				out[i*to+c] = v
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("convert: unsupported channel conversion: %d -> %d", from, to)
+	}
+}
+
+// SampleRate converts the interleaved samples with the given number of channels from one sample rate to another.
+//
+// It uses band-limited (windowed sinc) interpolation. When converting to a lower
+// sample rate, the frequencies above the new Nyquist frequency are filtered out to prevent aliasing.
+func SampleRate(in []float32, channels int, from, to float64) ([]float32, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("convert: invalid number of channels: %d", channels)
+	}
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("convert: invalid sample rate: %v -> %v", from, to)
+	}
+	if from == to {
+		return in, nil
+	}
+
+	ratio := to / from
+	cutoff := min(ratio, 1) // relative to the input Nyquist frequency
+	halfWidth := sincZeroCrossings / cutoff
+
+	frames := len(in) / channels
+	outFrames := int(math.Ceil(float64(frames) * ratio))
+	out := make([]float32, outFrames*channels)
+
+	for i := range outFrames {
+		t := float64(i) / ratio // position in input frames
+		first := max(int(math.Ceil(t-halfWidth)), 0)
+		last := min(int(math.Floor(t+halfWidth)), frames-1)
+
+		for c := range channels {
+			var sum float64
+			for k := first; k <= last; k++ {
+				x := t - float64(k)
+				sum += float64(in[k*channels+c]) * cutoff * sinc(cutoff*x) * blackman(x/halfWidth)
+			}
+			out[i*channels+c] = float32(sum)
+		}
+	}
+	return out, nil
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman returns the Blackman window at x in [-1, 1].
+func blackman(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
@@ -0,0 +1,86 @@
+package convert_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/convert"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/freq"
+	"github.com/stretchr/testify/assert"
+)
+
+func sine(n int, f, sr float64) []float32 {
+	s := make([]float32, n)
+	for i := range s {
+		s[i] = float32(math.Sin(2 * math.Pi * f * float64(i) / sr))
+	}
+	return s
+}
+
+func rms(s []float32) float64 {
+	var sum float64
+	for _, v := range s {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(s)))
+}
+
+func TestChannels(t *testing.T) {
+	mono, err := convert.Channels([]float32{1, 0, 0.5, 0.5}, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0.5, 0.5}, mono)
+
+	stereo, err := convert.Channels([]float32{1, 0.5}, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1, 1, 0.5, 0.5}, stereo)
+
+	_, err = convert.Channels([]float32{1, 2, 3}, 3, 2)
+	assert.Error(t, err)
+}
+
+func TestSampleRate(t *testing.T) {
+	in := sine(4410, 440, 44100)
+
+	out, err := convert.SampleRate(in, 1, 44100, 48000)
+	assert.NoError(t, err)
+	assert.Len(t, out, 4800)
+
+	// the converted signal matches the same sine sampled at the new rate (ignoring the edges)
+	want := sine(4800, 440, 48000)
+	for i := 100; i < 4700; i++ {
+		assert.InDelta(t, want[i], out[i], 1e-3)
+	}
+}
+
+func TestSampleRate_AntiAliasing(t *testing.T) {
+	// 20 kHz can't be represented at 16 kHz, so it has to be filtered out instead of aliasing
+	in := sine(44100, 20000, 44100)
+
+	out, err := convert.SampleRate(in, 1, 44100, 16000)
+	assert.NoError(t, err)
+	assert.Less(t, rms(out[1000:len(out)-1000]), 0.01)
+}
+
+func TestConvert(t *testing.T) {
+	from := afmt.Format{SampleRate: 48000 * freq.Hertz, NumChannels: 2}
+	to := afmt.Format{SampleRate: 24000 * freq.Hertz, NumChannels: 1}
+
+	in := make([]float32, 200)
+	for i := range in {
+		in[i] = 0.5
+	}
+
+	out, err := convert.Convert(in, from, to)
+	assert.NoError(t, err)
+	assert.Len(t, out, 50)
+	assert.InDelta(t, 0.5, out[25], 1e-3)
+}
+
+func TestNeedsConversion(t *testing.T) {
+	a := afmt.Format{SampleRate: 44100 * freq.Hertz, NumChannels: 1}
+	b := afmt.Format{SampleRate: 48000 * freq.Hertz, NumChannels: 1}
+
+	assert.False(t, convert.NeedsConversion(a, a))
+	assert.True(t, convert.NeedsConversion(a, b))
+}
@@ -0,0 +1,148 @@
+package effect
+
+import (
+	"math"
+	"time"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// Compressor is an [Effect] that reduces the dynamic range of the audio.
+// The channels are linked, so the stereo image doesn't shift.
+type Compressor struct {
+	// Threshold is the level in dBFS above which the gain is reduced.
+	Threshold float64
+
+	// Ratio is the compression ratio (e.g. 4 for 4:1).
+	Ratio float64
+
+	// Attack is the time it takes the gain reduction to react to a louder signal.
+	Attack time.Duration
+
+	// Release is the time it takes the gain reduction to recover.
+	Release time.Duration
+
+	// Makeup is the gain in dB applied after the compression.
+	Makeup float64
+}
+
+func newCompressor(p *params) (Effect, error) {
+	return Compressor{
+		Threshold: p.get("threshold", -18),
+		Ratio:     p.get("ratio", 4),
+		Attack:    msToDuration(p.get("attack", 5)),
+		Release:   msToDuration(p.get("release", 100)),
+		Makeup:    p.get("makeup", 0),
+	}, nil
+}
+
+func (c Compressor) Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader {
+	env := newEnvelope(format.SampleRate.Hertz(), c.Attack, c.Release)
+	makeup := dsp.DBToGain(c.Makeup)
+
+	return &reader{
+		src:      src,
+		channels: format.NumChannels,
+		process: func(frame []float32) {
+			var peak float64
+			for _, v := range frame {
+				peak = max(peak, math.Abs(float64(v)))
+			}
+			gain := dsp.DBToGain(gainReduction(env.follow(peak), c.Threshold, c.Ratio)) * makeup
+			for i := range frame {
+				frame[i] *= float32(gain)
+			}
+		},
+	}
+}
+
+// DeEsser is an [Effect] that tames sibilance ("s" and "sh" sounds) by reducing the gain
+// while the high frequencies are too loud.
+type DeEsser struct {
+	// Frequency is the frequency in Hz above which sibilance is detected.
+	Frequency float64
+
+	// Threshold is the level in dBFS of the high frequencies above which the gain is reduced.
+	Threshold float64
+
+	// Ratio is the compression ratio of the sibilance.
+	Ratio float64
+}
+
+func newDeEsser(p *params) (Effect, error) {
+	return DeEsser{
+		Frequency: p.get("frequency", 6000),
+		Threshold: p.get("threshold", -30),
+		Ratio:     p.get("ratio", 4),
+	}, nil
+}
+
+func (d DeEsser) Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader {
+	sr := format.SampleRate.Hertz()
+	ch := format.NumChannels
+
+	sidechain := make([]dsp.Biquad, ch)
+	for c := range sidechain {
+		sidechain[c] = dsp.HighPass(sr, d.Frequency, 0.7071)
+	}
+	env := newEnvelope(sr, time.Millisecond, 50*time.Millisecond)
+
+	return &reader{
+		src:      src,
+		channels: ch,
+		process: func(frame []float32) {
+			var peak float64
+			for c, v := range frame {
+				peak = max(peak, math.Abs(sidechain[c].Process(float64(v))))
+			}
+			gain := dsp.DBToGain(gainReduction(env.follow(peak), d.Threshold, d.Ratio))
+			for i := range frame {
+				frame[i] *= float32(gain)
+			}
+		},
+	}
+}
+
+// envelope is a peak envelope follower with separate attack and release times.
+type envelope struct {
+	attack, release float64 // coefficients per frame
+	level           float64
+}
+
+func newEnvelope(sr float64, attack, release time.Duration) *envelope {
+	coef := func(d time.Duration) float64 {
+		if d <= 0 {
+			return 0
+		}
+		return math.Exp(-1 / (d.Seconds() * sr))
+	}
+	return &envelope{attack: coef(attack), release: coef(release)}
+}
+
+// follow returns the envelope level in dBFS after the peak.
+func (e *envelope) follow(peak float64) float64 {
+	coef := e.release
+	if peak > e.level {
+		coef = e.attack
+	}
+	e.level = peak + (e.level-peak)*coef
+	return gainToDB(e.level)
+}
+
+// gainReduction returns the gain in dB (0 or negative) for the level with the threshold and ratio.
+func gainReduction(level, threshold, ratio float64) float64 {
+	if level <= threshold || ratio <= 1 {
+		return 0
+	}
+	return (threshold - level) * (1 - 1/ratio)
+}
+
+func gainToDB(gain float64) float64 {
+	return 20 * math.Log10(max(gain, 1e-10))
+}
+
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
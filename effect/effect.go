@@ -0,0 +1,181 @@
+// Package effect implements streaming post-processing effects (EQ, dynamics and reverb)
+// for the rendered vocals, so they can be finished without a DAW.
+package effect
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// Effect is a streaming audio effect.
+//
+// An Effect only holds the configuration; the state of the processing lives in the readers
+// returned by Apply, so one Effect can process multiple sources.
+type Effect interface {
+	// Apply returns a reader that reads the interleaved audio from src (in the format) with the effect applied.
+	// The source must return whole frames.
+	Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader
+}
+
+// Chain is an [Effect] that applies its effects in order.
+type Chain []Effect
+
+func (c Chain) Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader {
+	for _, e := range c {
+		src = e.Apply(src, format)
+	}
+	return src
+}
+
+// factories maps effect types to functions that create effects from parameters.
+var factories = map[string]func(p *params) (Effect, error){
+	"eq":         newEQ,
+	"compressor": newCompressor,
+	"deesser":    newDeEsser,
+	"reverb":     newReverb,
+}
+
+// New creates the effect described by the configuration. The supported types are
+// eq (see [NewEQ]), compressor (see [Compressor]), deesser (see [DeEsser]) and reverb (see [Reverb]).
+func New(cfg sequence.EffectConfig) (Effect, error) {
+	factory, ok := factories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown effect type: %s", cfg.Type)
+	}
+
+	p := &params{m: cfg.Params, used: make(map[string]bool)}
+	e, err := factory(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s effect: %w", cfg.Type, err)
+	}
+	if err := p.checkUnused(); err != nil {
+		return nil, fmt.Errorf("invalid %s effect: %w", cfg.Type, err)
+	}
+	return e, nil
+}
+
+// NewChain creates a [Chain] of the effects described by the configurations.
+func NewChain(cfgs []sequence.EffectConfig) (Chain, error) {
+	chain := make(Chain, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		e, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, e)
+	}
+	return chain, nil
+}
+
+// FromTrack creates the effect chain of the track.
+func FromTrack(track sequence.Track) (Chain, error) {
+	return NewChain(track.Effects)
+}
+
+// ParseChain parses an effect chain in the compact form used on the command line: the effects
+// are separated by semicolons, and each one is its type optionally followed by a colon and
+// comma-separated parameters, e.g. "eq:lowcut=80,high=2;compressor:threshold=-20;reverb:mix=0.15".
+func ParseChain(s string) ([]sequence.EffectConfig, error) {
+	var cfgs []sequence.EffectConfig
+	for part := range strings.SplitSeq(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		typ, rawParams, _ := strings.Cut(part, ":")
+		cfg := sequence.EffectConfig{Type: strings.TrimSpace(typ), Params: make(map[string]float64)}
+		for kv := range strings.SplitSeq(rawParams, ",") {
+			kv = strings.TrimSpace(kv)
+			if kv == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s effect parameter: %s", cfg.Type, kv)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s effect parameter %s: %w", cfg.Type, key, err)
+			}
+			cfg.Params[strings.TrimSpace(key)] = v
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+// params gives access to the parameters of an effect configuration and tracks which ones were used.
+type params struct {
+	m    map[string]float64
+	used map[string]bool
+}
+
+// get returns the parameter with the name, or def if it's missing.
+func (p *params) get(name string, def float64) float64 {
+	p.used[name] = true
+	if v, ok := p.m[name]; ok {
+		return v
+	}
+	return def
+}
+
+func (p *params) checkUnused() error {
+	for _, name := range slices.Sorted(maps.Keys(p.m)) {
+		if !p.used[name] {
+			return fmt.Errorf("unknown parameter: %s", name)
+		}
+	}
+	return nil
+}
+
+// reader applies a frame processor to the audio of a source.
+type reader struct {
+	src      aio.SampleReader
+	channels int
+	process  func(frame []float32) // processes a frame in place
+	tail     int                   // number of frames to process after the end of the source (e.g. a reverb tail)
+	eof      bool
+}
+
+func (r *reader) ReadSamples(p []float32) (int, error) {
+	ch := r.channels
+	frames := len(p) / ch
+	if frames == 0 {
+		return 0, nil
+	}
+
+	if !r.eof {
+		n, err := r.src.ReadSamples(p[:frames*ch])
+		n -= n % ch
+		for i := 0; i < n; i += ch {
+			r.process(p[i : i+ch])
+		}
+		if err != io.EOF {
+			return n, err
+		}
+		r.eof = true
+		if n > 0 {
+			return n, nil
+		}
+	}
+
+	if r.tail == 0 {
+		return 0, io.EOF
+	}
+	n := min(frames, r.tail)
+	r.tail -= n
+	clear(p[:n*ch])
+	for i := 0; i < n*ch; i += ch {
+		r.process(p[i : i+ch])
+	}
+	return n * ch, nil
+}
@@ -0,0 +1,118 @@
+package effect_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/effect"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/SladkyCitron/resona/freq"
+	"github.com/stretchr/testify/assert"
+)
+
+var mono = afmt.Format{SampleRate: 44100 * freq.Hertz, NumChannels: 1}
+
+func sine(hz, amp float64, n int) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(amp * math.Sin(2*math.Pi*hz*float64(i)/44100))
+	}
+	return out
+}
+
+func readAll(t *testing.T, r aio.SampleReader) []float32 {
+	t.Helper()
+	out, err := dsp.ReadAll(r)
+	assert.NoError(t, err)
+	return out
+}
+
+func peak(s []float32) float64 {
+	var p float64
+	for _, v := range s {
+		p = max(p, math.Abs(float64(v)))
+	}
+	return p
+}
+
+func TestParseChain(t *testing.T) {
+	cfgs, err := effect.ParseChain("eq:lowcut=80, high=2; compressor ;reverb:mix=0.15")
+	assert.NoError(t, err)
+	assert.Equal(t, []sequence.EffectConfig{
+		{Type: "eq", Params: map[string]float64{"lowcut": 80, "high": 2}},
+		{Type: "compressor", Params: map[string]float64{}},
+		{Type: "reverb", Params: map[string]float64{"mix": 0.15}},
+	}, cfgs)
+
+	_, err = effect.ParseChain("eq:lowcut")
+	assert.Error(t, err)
+	_, err = effect.ParseChain("eq:lowcut=miku")
+	assert.Error(t, err)
+}
+
+func TestNewChain(t *testing.T) {
+	chain, err := effect.NewChain([]sequence.EffectConfig{{Type: "eq"}, {Type: "deesser"}, {Type: "reverb"}})
+	assert.NoError(t, err)
+	assert.Len(t, chain, 3)
+
+	_, err = effect.New(sequence.EffectConfig{Type: "flanger"})
+	assert.ErrorContains(t, err, "unknown effect type")
+	_, err = effect.New(sequence.EffectConfig{Type: "eq", Params: map[string]float64{"bass": 3}})
+	assert.ErrorContains(t, err, "unknown parameter: bass")
+	_, err = effect.New(sequence.EffectConfig{Type: "reverb", Params: map[string]float64{"mix": 2}})
+	assert.Error(t, err)
+}
+
+func TestEQ(t *testing.T) {
+	eq := effect.EQ{{Type: effect.BandHighPass, Frequency: 1000}}
+
+	low := readAll(t, eq.Apply(dsp.NewSliceReader(sine(50, 1, 44100)), mono))
+	high := readAll(t, eq.Apply(dsp.NewSliceReader(sine(10000, 1, 44100)), mono))
+
+	assert.Len(t, low, 44100)
+	assert.Less(t, peak(low[22050:]), 0.01)
+	assert.InDelta(t, 1, peak(high[22050:]), 0.02)
+}
+
+func TestCompressor(t *testing.T) {
+	c := effect.Compressor{Threshold: -20, Ratio: 4}
+
+	out := readAll(t, c.Apply(dsp.NewSliceReader(sine(440, 1, 44100)), mono))
+
+	// 0 dB is 20 dB above the threshold, which is reduced to 5 dB
+	assert.InDelta(t, -15, 20*math.Log10(peak(out[22050:])), 0.5)
+}
+
+func TestDeEsser(t *testing.T) {
+	d := effect.DeEsser{Frequency: 6000, Threshold: -30, Ratio: 4}
+
+	sibilant := readAll(t, d.Apply(dsp.NewSliceReader(sine(8000, 0.5, 44100)), mono))
+	voiced := readAll(t, d.Apply(dsp.NewSliceReader(sine(300, 0.5, 44100)), mono))
+
+	// only the high frequencies are reduced
+	assert.Less(t, peak(sibilant[22050:]), 0.1)
+	assert.InDelta(t, 0.5, peak(voiced[22050:]), 0.05)
+}
+
+func TestReverb(t *testing.T) {
+	in := make([]float32, 4410)
+	in[0] = 1
+	r := effect.Reverb{RoomSize: 0.5, Damping: 0.5, Mix: 0.5}
+
+	out := readAll(t, r.Apply(dsp.NewSliceReader(in), mono))
+
+	assert.Greater(t, len(out), len(in)) // the tail
+	assert.Equal(t, float32(0.5), out[0])
+	assert.NotZero(t, peak(out[len(in):]))
+}
+
+func TestChain(t *testing.T) {
+	in := sine(440, 0.5, 1000)
+	chain := effect.Chain{effect.EQ{}, effect.EQ{}}
+
+	out := readAll(t, chain.Apply(dsp.NewSliceReader(append([]float32(nil), in...)), mono))
+	assert.Equal(t, in, out)
+}
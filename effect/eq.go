@@ -0,0 +1,127 @@
+package effect
+
+import (
+	"fmt"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// BandType is the type of an [EQ] band.
+type BandType uint8
+
+const (
+	BandPeak BandType = iota
+	BandLowShelf
+	BandHighShelf
+	BandLowPass
+	BandHighPass
+)
+
+// Band represents a single band of an [EQ].
+type Band struct {
+	// Type is the type of the band.
+	Type BandType
+
+	// Frequency is the center, corner or cutoff frequency in Hz.
+	Frequency float64
+
+	// Gain is the gain in dB. It's ignored by low-pass and high-pass bands.
+	Gain float64
+
+	// Q is the quality factor (the inverse of the bandwidth). If it's 0, 0.7071 (Butterworth) is used.
+	Q float64
+}
+
+// EQ is an [Effect] that applies a biquad filter for every band in order.
+type EQ []Band
+
+// NewEQ creates an [EQ] with the most common bands for vocals. The cuts and the frequencies are in Hz,
+// the gains in dB. Bands with a zero cut or gain are left out.
+func NewEQ(lowCut, lowFreq, low, midFreq, mid, midQ, highFreq, high, highCut float64) EQ {
+	var eq EQ
+	if lowCut > 0 {
+		eq = append(eq, Band{Type: BandHighPass, Frequency: lowCut})
+	}
+	if low != 0 {
+		eq = append(eq, Band{Type: BandLowShelf, Frequency: lowFreq, Gain: low})
+	}
+	if mid != 0 {
+		eq = append(eq, Band{Type: BandPeak, Frequency: midFreq, Gain: mid, Q: midQ})
+	}
+	if high != 0 {
+		eq = append(eq, Band{Type: BandHighShelf, Frequency: highFreq, Gain: high})
+	}
+	if highCut > 0 {
+		eq = append(eq, Band{Type: BandLowPass, Frequency: highCut})
+	}
+	return eq
+}
+
+func newEQ(p *params) (Effect, error) {
+	eq := NewEQ(
+		p.get("lowcut", 0),
+		p.get("lowfreq", 200),
+		p.get("low", 0),
+		p.get("midfreq", 1000),
+		p.get("mid", 0),
+		p.get("midq", 1),
+		p.get("highfreq", 5000),
+		p.get("high", 0),
+		p.get("highcut", 0),
+	)
+	for _, b := range eq {
+		if b.Frequency <= 0 {
+			return nil, fmt.Errorf("invalid frequency: %g", b.Frequency)
+		}
+	}
+	return eq, nil
+}
+
+// filter returns the biquad filter of the band at the sample rate.
+func (b Band) filter(sr float64) dsp.Biquad {
+	q := b.Q
+	if q == 0 {
+		q = 0.7071
+	}
+	switch b.Type {
+	case BandLowShelf:
+		return dsp.LowShelf(sr, b.Frequency, q, b.Gain)
+	case BandHighShelf:
+		return dsp.HighShelf(sr, b.Frequency, q, b.Gain)
+	case BandLowPass:
+		return dsp.LowPass(sr, b.Frequency, q)
+	case BandHighPass:
+		return dsp.HighPass(sr, b.Frequency, q)
+	default:
+		return dsp.Peak(sr, b.Frequency, q, b.Gain)
+	}
+}
+
+func (eq EQ) Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader {
+	sr := format.SampleRate.Hertz()
+	ch := format.NumChannels
+
+	// one filter per band and channel
+	filters := make([]dsp.Biquad, len(eq)*ch)
+	for i, b := range eq {
+		for c := range ch {
+			filters[i*ch+c] = b.filter(sr)
+		}
+	}
+
+	return &reader{
+		src:      src,
+		channels: ch,
+		process: func(frame []float32) {
+			for c := range frame {
+				x := float64(frame[c])
+				for i := range eq {
+					x = filters[i*ch+c].Process(x)
+				}
+				frame[c] = float32(x)
+			}
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package effect
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// Delay lengths of the Freeverb comb and allpass filters in samples at 44.1 kHz.
+var (
+	reverbCombs     = [...]int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllpasses = [...]int{556, 441, 341, 225}
+)
+
+const (
+	reverbSpread   = 23 // extra delay of the filters of odd channels, which decorrelates stereo channels
+	reverbInput    = 0.015
+	reverbWet      = 3
+	reverbFeedback = 0.5 // of the allpass filters
+)
+
+// Reverb is a simple algorithmic reverb [Effect] (Schroeder-Moorer, as in Freeverb).
+// The output is longer than the source by the reverb tail.
+type Reverb struct {
+	// RoomSize is the size of the room from 0 to 1, which controls the length of the tail.
+	RoomSize float64
+
+	// Damping is how much the high frequencies are absorbed from 0 to 1.
+	Damping float64
+
+	// Mix is the ratio of the reverberated (wet) signal from 0 (dry) to 1 (wet).
+	Mix float64
+}
+
+func newReverb(p *params) (Effect, error) {
+	r := Reverb{
+		RoomSize: p.get("room", 0.5),
+		Damping:  p.get("damping", 0.5),
+		Mix:      p.get("mix", 0.2),
+	}
+	for name, v := range map[string]float64{"room": r.RoomSize, "damping": r.Damping, "mix": r.Mix} {
+		if v < 0 || v > 1 {
+			return nil, fmt.Errorf("%s must be between 0 and 1, got %g", name, v)
+		}
+	}
+	return r, nil
+}
+
+func (r Reverb) Apply(src aio.SampleReader, format afmt.Format) aio.SampleReader {
+	sr := format.SampleRate.Hertz()
+	ch := format.NumChannels
+	scale := sr / 44100
+	feedback := 0.7 + 0.28*r.RoomSize
+
+	type channel struct {
+		combs     []comb
+		allpasses []allpass
+	}
+	channels := make([]channel, ch)
+	longest := 0
+	for c := range channels {
+		spread := 0
+		if c%2 == 1 {
+			spread = reverbSpread
+		}
+		for _, n := range reverbCombs {
+			size := max(int(float64(n+spread)*scale), 1)
+			longest = max(longest, size)
+			channels[c].combs = append(channels[c].combs, comb{buf: make([]float64, size), feedback: feedback, damping: r.Damping})
+		}
+		for _, n := range reverbAllpasses {
+			size := max(int(float64(n+spread)*scale), 1)
+			channels[c].allpasses = append(channels[c].allpasses, allpass{buf: make([]float64, size)})
+		}
+	}
+
+	// the tail ends when the combs have decayed by 60 dB
+	tail := int(math.Ceil(math.Log(1e-3)/math.Log(feedback))) * longest
+
+	return &reader{
+		src:      src,
+		channels: ch,
+		tail:     tail,
+		process: func(frame []float32) {
+			// all channels share the (mono) input of the reverb
+			var in float64
+			for _, v := range frame {
+				in += float64(v)
+			}
+			in *= reverbInput / float64(ch)
+
+			for c := range frame {
+				var out float64
+				for i := range channels[c].combs {
+					out += channels[c].combs[i].process(in)
+				}
+				for i := range channels[c].allpasses {
+					out = channels[c].allpasses[i].process(out)
+				}
+				frame[c] = float32(float64(frame[c])*(1-r.Mix) + out*reverbWet*r.Mix)
+			}
+		},
+	}
+}
+
+// comb is a lowpass-feedback comb filter.
+type comb struct {
+	buf               []float64
+	pos               int
+	feedback, damping float64
+	store             float64
+}
+
+func (f *comb) process(x float64) float64 {
+	y := f.buf[f.pos]
+	f.store = y*(1-f.damping) + f.store*f.damping
+	f.buf[f.pos] = x + f.store*f.feedback
+	f.pos = (f.pos + 1) % len(f.buf)
+	return y
+}
+
+// allpass is a Schroeder allpass filter.
+type allpass struct {
+	buf []float64
+	pos int
+}
+
+func (f *allpass) process(x float64) float64 {
+	buffered := f.buf[f.pos]
+	y := buffered - x
+	f.buf[f.pos] = x + buffered*reverbFeedback
+	f.pos = (f.pos + 1) % len(f.buf)
+	return y
+}
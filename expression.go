@@ -0,0 +1,87 @@
+package gotau
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SladkyCitron/gotau/sequence"
+)
+
+// SetExpressions sets the expression curves, which map resampler flags to their values over time.
+// Every note gets the value of each curve at its start as a flag. Flags set by the note itself
+// take precedence over the curves.
+//
+// [Synth.EnqueueSequence] sets the expressions of the sequence.
+func (s *Synth) SetExpressions(expressions map[string]sequence.Curve) {
+	s.expressions = expressions
+}
+
+// getFlags returns the resampler flags of the note with the expressions merged in.
+func (s *Synth) getFlags(note sequence.Note) string {
+	if len(s.expressions) == 0 {
+		return note.Flags
+	}
+
+	own := flagNames(note.Flags)
+
+	var b strings.Builder
+	b.WriteString(note.Flags)
+	// sorted, so the flags (and the resampler cache keys) are stable
+	for _, name := range slices.Sorted(maps.Keys(s.expressions)) {
+		if slices.Contains(own, name) {
+			continue
+		}
+		v, ok := expressionAt(s.expressions[name], note.Position)
+		if !ok {
+			continue
+		}
+		b.WriteString(name)
+		b.WriteString(strconv.Itoa(int(math.Round(v))))
+	}
+	return b.String()
+}
+
+// expressionAt returns the value of the expression curve at the tick.
+// The first and last values are held before and after the curve.
+func expressionAt(c sequence.Curve, tick int) (float64, bool) {
+	if len(c) == 0 {
+		return 0, false
+	}
+	if tick <= c[0].X {
+		return c[0].Y, true
+	}
+	if last := c[len(c)-1]; tick >= last.X {
+		return last.Y, true
+	}
+	v := c.At(tick)
+	return v, !math.IsNaN(v)
+}
+
+// flagNames returns the names of the flags in the flag string (e.g. "g-5B0bri" has g, B and bri).
+// A flag is a run of letters followed by an optional signed number.
+func flagNames(flags string) []string {
+	var names []string
+	for i := 0; i < len(flags); {
+		start := i
+		for i < len(flags) && isFlagLetter(flags[i]) {
+			i++
+		}
+		if i > start {
+			names = append(names, flags[start:i])
+		}
+		if i < len(flags) && (flags[i] == '-' || flags[i] == '+') {
+			i++
+		}
+		for i < len(flags) && !isFlagLetter(flags[i]) {
+			i++
+		}
+	}
+	return names
+}
+
+func isFlagLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,44 @@
+package gotau_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynth_Expressions(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a"},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 0, Duration: 480, Lyric: "a", Note: 60, Intensity: 1, Flags: "B10"},
+			{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+			{Position: 960, Duration: 480, Lyric: "a", Note: 60, Intensity: 1, Flags: "g+3Y50"},
+			{Position: 1920, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+		},
+		Expressions: map[string]sequence.Curve{
+			sequence.ExpressionGender: {
+				{X: 0, Y: 0, Interp: sequence.CurveInterpolationLinear},
+				{X: 960, Y: -10},
+			},
+			sequence.ExpressionBrightness: {
+				{X: 480, Y: 20},
+			},
+		},
+	}
+
+	plan := synth.Plan(seq)
+	assert.Equal(t, "B10bri20g0", plan.Notes[0].ResampleConfig.Flags)
+	assert.Equal(t, "bri20g-5", plan.Notes[1].ResampleConfig.Flags)
+	assert.Equal(t, "g+3Y50bri20", plan.Notes[2].ResampleConfig.Flags)
+	assert.Equal(t, "bri20g-10", plan.Notes[3].ResampleConfig.Flags)
+}
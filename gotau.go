@@ -1,5 +1,7 @@
 package gotau
 
+import "time"
+
 const (
 	// ResamplerDiskCacheDir is the name of the subdirectory in the user's cache directory where
 	// resampled notes will be cached when using diskcache.
@@ -10,4 +12,40 @@ const (
 )
 
 // Progress represents the rendering progress information.
-type Progress struct{}
+type Progress struct {
+	// NotesRendered is the number of notes rendered so far.
+	NotesRendered int
+
+	// NotesTotal is the total number of notes enqueued.
+	NotesTotal int
+
+	// CacheHits is the number of notes loaded from the resampler cache.
+	CacheHits int
+
+	// CacheMisses is the number of notes that had to be resampled.
+	CacheMisses int
+
+	// Elapsed is the time elapsed since rendering started.
+	Elapsed time.Duration
+}
+
+// Percent returns the percentage of rendered notes (0 to 100).
+func (p Progress) Percent() float64 {
+	if p.NotesTotal == 0 {
+		return 100
+	}
+	return float64(p.NotesRendered) / float64(p.NotesTotal) * 100
+}
+
+// ETA returns the estimated remaining time, extrapolated from the elapsed time.
+// It returns 0 if no notes have been rendered yet.
+func (p Progress) ETA() time.Duration {
+	if p.NotesRendered == 0 {
+		return 0
+	}
+	perNote := p.Elapsed / time.Duration(p.NotesRendered)
+	return perNote * time.Duration(p.NotesTotal-p.NotesRendered)
+}
+
+// ProgressFunc is a function that receives rendering progress updates.
+type ProgressFunc func(p Progress)
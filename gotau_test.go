@@ -0,0 +1,74 @@
+package gotau_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/cache/memcache"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	p := gotau.Progress{NotesRendered: 25, NotesTotal: 100, Elapsed: 10 * time.Second}
+
+	assert.Equal(t, 25.0, p.Percent())
+	assert.Equal(t, 30*time.Second, p.ETA())
+}
+
+func TestProgress_Empty(t *testing.T) {
+	p := gotau.Progress{}
+
+	assert.Equal(t, 100.0, p.Percent())
+	assert.Equal(t, time.Duration(0), p.ETA())
+}
+
+func TestSynth_SetProgressFunc(t *testing.T) {
+	vb := openTestVoicebank(t)
+	c := memcache.New()
+
+	// the first note starts a phrase and gets a breath, and the last one doesn't resolve
+	seq := testSeq
+	seq.Notes = append(slices.Clone(testSeq.Notes), sequence.Note{Position: 2400, Duration: 480, Lyric: "xyz", Note: 60, Intensity: 1})
+
+	render := func() []gotau.Progress {
+		synth := gotau.New(44100, vb, loopResampler{}, nil)
+		synth.SetResamplerCache(c)
+		synth.SetBreath(&gotau.Breath{MinRest: 300, Length: 100, Volume: 0.5, Aliases: []string{"i"}})
+		var reports []gotau.Progress
+		synth.SetProgressFunc(func(p gotau.Progress) {
+			reports = append(reports, p)
+		})
+		synth.EnqueueSequence(seq)
+		renderAll(t, synth)
+		return reports
+	}
+
+	first := render()
+	if assert.Len(t, first, 5) { // the breath is reported, but isn't counted as a note
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, notesRendered(first))
+		last := first[4]
+		assert.Equal(t, 4, last.NotesTotal)
+		assert.Equal(t, 4, last.CacheMisses) // the silent note isn't resampled
+		assert.Zero(t, last.CacheHits)
+		assert.Equal(t, 100.0, last.Percent())
+	}
+
+	second := render()
+	if assert.Len(t, second, 5) {
+		last := second[4]
+		assert.Equal(t, 4, last.NotesRendered)
+		assert.Equal(t, 4, last.CacheHits)
+		assert.Zero(t, last.CacheMisses)
+	}
+}
+
+func notesRendered(reports []gotau.Progress) []int {
+	var n []int
+	for _, p := range reports {
+		n = append(n, p.NotesRendered)
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 package gotau
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"io"
 
@@ -8,12 +9,13 @@ import (
 	"github.com/SladkyCitron/gotau/resample"
 )
 
-func (s *Synth) getKeyFunc(cfg resample.ResampleConfig) cache.KeyFunc {
+func (s *Synth) getKeyFunc(sampleHash [sha256.Size]byte, cfg resample.ResampleConfig) cache.KeyFunc {
 	return func(w io.Writer) {
 		_, _ = w.Write([]byte("gotau-resample"))
 		_, _ = w.Write([]byte(s.res.ID()))
-		_ = binary.Write(w, binary.LittleEndian, uint64(s.vbFileBuf.Len()))
-		_, _ = w.Write(s.vbFileBuf.Bytes())
+		_, _ = w.Write(sampleHash[:])
+		_ = binary.Write(w, binary.LittleEndian, cfg.AudioFormat.SampleRate.Hertz())
+		_ = binary.Write(w, binary.LittleEndian, uint64(cfg.AudioFormat.NumChannels))
 		_, _ = w.Write([]byte{byte(cfg.Pitch)})
 		_ = binary.Write(w, binary.LittleEndian, cfg.Velocity)
 		_, _ = w.Write([]byte(cfg.Flags))
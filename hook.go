@@ -0,0 +1,105 @@
+package gotau
+
+import (
+	"context"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// HookNote represents a note being rendered, passed into the functions of a [Hook].
+type HookNote struct {
+	// Note is the note.
+	Note sequence.Note
+
+	// OtoEntry is the resolved oto entry. Changing it changes the sample that is resampled.
+	OtoEntry voicebank.OtoEntry
+
+	// ResampleConfig is the configuration passed into the resampler.
+	ResampleConfig resample.ResampleConfig
+
+	// ConcatConfig is the configuration passed into the concatenator (e.g. for per-note gain tweaks).
+	// Its Overlap is computed during concatenation and can't be changed. It's unused by a [Stream].
+	ConcatConfig concat.Config
+}
+
+// Hook is a render middleware that lets applications process notes without modifying the [Synth].
+// Both functions are optional. Hooks are called in the order they were added.
+type Hook struct {
+	// BeforeResample is called before the note is resampled (or loaded from the resampler cache),
+	// after its timing has been planned. It can inspect and modify the note's oto entry and configurations
+	// (e.g. to inject flags). It's called in order on the goroutine that calls ReadSamples
+	// (or [Synth.Plan], or the note-on methods of a [Stream]).
+	//
+	// Returning an error stops rendering.
+	BeforeResample func(ctx context.Context, n *HookNote) error
+
+	// AfterResample is called with the resampled audio of the note before it's concatenated
+	// and returns the processed audio (e.g. de-essed). The resampler cache stores the audio
+	// before processing.
+	//
+	// When concurrency is greater than 1, it's called concurrently for different notes.
+	// Returning an error stops rendering.
+	AfterResample func(ctx context.Context, n *HookNote, resampled aio.SampleReader) (aio.SampleReader, error)
+}
+
+// AddHook adds a render middleware hook to the Synth.
+func (s *Synth) AddHook(h Hook) {
+	s.hooks = append(s.hooks, h)
+}
+
+// beforeResample runs the BeforeResample hooks on the planned job.
+func (s *Synth) beforeResample(job *renderJob) error {
+	if len(s.hooks) == 0 {
+		return nil
+	}
+
+	n := job.hookNote()
+	for _, h := range s.hooks {
+		if h.BeforeResample == nil {
+			continue
+		}
+		if err := h.BeforeResample(s.ctx, n); err != nil {
+			return err
+		}
+	}
+
+	job.otoEntry = n.OtoEntry
+	job.resampleCfg = n.ResampleConfig
+	overlap := job.concatCfg.Overlap
+	job.concatCfg = n.ConcatConfig
+	job.concatCfg.Overlap = overlap
+	return nil
+}
+
+// afterResample runs the AfterResample hooks on the resampled audio of the job.
+func (s *Synth) afterResample(ctx context.Context, job *renderJob, resampled aio.SampleReader) (aio.SampleReader, error) {
+	if len(s.hooks) == 0 {
+		return resampled, nil
+	}
+
+	n := job.hookNote()
+	for _, h := range s.hooks {
+		if h.AfterResample == nil {
+			continue
+		}
+		var err error
+		resampled, err = h.AfterResample(ctx, n, resampled)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resampled, nil
+}
+
+func (job *renderJob) hookNote() *HookNote {
+	return &HookNote{
+		Note:           job.note,
+		OtoEntry:       job.otoEntry,
+		ResampleConfig: job.resampleCfg,
+		ConcatConfig:   job.concatCfg,
+	}
+}
@@ -0,0 +1,71 @@
+package gotau_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+var hookTestSeq = sequence.Sequence{
+	Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+	Notes: []sequence.Note{
+		{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+	},
+}
+
+func TestSynth_AddHook(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Directory: "cv", Alias: "a", Preutterance: 100, Overlap: 20},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+
+	var calls []string
+	synth.AddHook(gotau.Hook{
+		BeforeResample: func(ctx context.Context, n *gotau.HookNote) error {
+			calls = append(calls, "first")
+			n.ResampleConfig.Flags += "g-5"
+			n.ConcatConfig.Intensity = 0.5
+			return nil
+		},
+	})
+	synth.AddHook(gotau.Hook{
+		BeforeResample: func(ctx context.Context, n *gotau.HookNote) error {
+			calls = append(calls, "second")
+			n.ResampleConfig.Flags += "B0"
+			return nil
+		},
+	})
+
+	plan := synth.Plan(hookTestSeq)
+	assert.Equal(t, []string{"first", "second"}, calls)
+	assert.Equal(t, "g-5B0", plan.Notes[0].ResampleConfig.Flags)
+	assert.Equal(t, 0.5, plan.Notes[0].ConcatConfig.Intensity)
+	assert.Equal(t, 20.0, plan.Notes[0].ConcatConfig.Overlap)
+}
+
+func TestSynth_AddHook_Error(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Directory: "cv", Alias: "a", Preutterance: 100, Overlap: 20},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+
+	errHook := errors.New("hook failed")
+	synth.AddHook(gotau.Hook{
+		BeforeResample: func(ctx context.Context, n *gotau.HookNote) error {
+			return errHook
+		},
+	})
+	synth.EnqueueSequence(hookTestSeq)
+
+	_, err := synth.ReadSamples(make([]float32, 4096))
+	assert.ErrorIs(t, err, errHook)
+}
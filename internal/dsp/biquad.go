@@ -0,0 +1,86 @@
+package dsp
+
+import "math"
+
+// Biquad is a biquad filter in the transposed direct form II.
+// The coefficients are normalized, so a0 is 1.
+type Biquad struct {
+	B0, B1, B2, A1, A2 float64
+
+	z1, z2 float64
+}
+
+// Process filters a single sample.
+func (f *Biquad) Process(x float64) float64 {
+	y := f.B0*x + f.z1
+	f.z1 = f.B1*x - f.A1*y + f.z2
+	f.z2 = f.B2*x - f.A2*y
+	return y
+}
+
+// The following filters are from the Audio EQ Cookbook by Robert Bristow-Johnson.
+// The frequencies and the sample rate are in Hz and the gains in dB.
+
+// LowPass returns a second-order low-pass filter.
+func LowPass(sr, freq, q float64) Biquad {
+	w, alpha := cookbook(sr, freq, q)
+	cos := math.Cos(w)
+	return normalize(
+		(1-cos)/2, 1-cos, (1-cos)/2,
+		1+alpha, -2*cos, 1-alpha,
+	)
+}
+
+// HighPass returns a second-order high-pass filter.
+func HighPass(sr, freq, q float64) Biquad {
+	w, alpha := cookbook(sr, freq, q)
+	cos := math.Cos(w)
+	return normalize(
+		(1+cos)/2, -(1 + cos), (1+cos)/2,
+		1+alpha, -2*cos, 1-alpha,
+	)
+}
+
+// Peak returns a peaking EQ filter.
+func Peak(sr, freq, q, gain float64) Biquad {
+	w, alpha := cookbook(sr, freq, q)
+	cos := math.Cos(w)
+	a := math.Pow(10, gain/40)
+	return normalize(
+		1+alpha*a, -2*cos, 1-alpha*a,
+		1+alpha/a, -2*cos, 1-alpha/a,
+	)
+}
+
+// LowShelf returns a low shelf filter.
+func LowShelf(sr, freq, q, gain float64) Biquad {
+	w, alpha := cookbook(sr, freq, q)
+	cos := math.Cos(w)
+	a := math.Pow(10, gain/40)
+	sq := 2 * math.Sqrt(a) * alpha
+	return normalize(
+		a*((a+1)-(a-1)*cos+sq), 2*a*((a-1)-(a+1)*cos), a*((a+1)-(a-1)*cos-sq),
+		(a+1)+(a-1)*cos+sq, -2*((a-1)+(a+1)*cos), (a+1)+(a-1)*cos-sq,
+	)
+}
+
+// HighShelf returns a high shelf filter.
+func HighShelf(sr, freq, q, gain float64) Biquad {
+	w, alpha := cookbook(sr, freq, q)
+	cos := math.Cos(w)
+	a := math.Pow(10, gain/40)
+	sq := 2 * math.Sqrt(a) * alpha
+	return normalize(
+		a*((a+1)+(a-1)*cos+sq), -2*a*((a-1)+(a+1)*cos), a*((a+1)+(a-1)*cos-sq),
+		(a+1)-(a-1)*cos+sq, 2*((a-1)-(a+1)*cos), (a+1)-(a-1)*cos-sq,
+	)
+}
+
+func cookbook(sr, freq, q float64) (w, alpha float64) {
+	w = 2 * math.Pi * min(freq, sr*0.49) / sr
+	return w, math.Sin(w) / (2 * q)
+}
+
+func normalize(b0, b1, b2, a0, a1, a2 float64) Biquad {
+	return Biquad{B0: b0 / a0, B1: b1 / a0, B2: b2 / a0, A1: a1 / a0, A2: a2 / a0}
+}
@@ -0,0 +1,8 @@
+package dsp
+
+import "math"
+
+// DBToGain converts a level in decibels to a linear gain.
+func DBToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
@@ -0,0 +1,46 @@
+package dsp
+
+import (
+	"io"
+
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// SliceReader is a sample reader that reads from a slice.
+type SliceReader struct {
+	s []float32
+}
+
+// NewSliceReader returns a [SliceReader] that reads the samples.
+func NewSliceReader(samples []float32) *SliceReader {
+	return &SliceReader{s: samples}
+}
+
+func (r *SliceReader) ReadSamples(p []float32) (int, error) {
+	if len(r.s) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.s)
+	r.s = r.s[n:]
+	return n, nil
+}
+
+// ReadAll reads from r until [io.EOF] and returns the samples.
+// A read that returns neither samples nor an error is reported as [io.ErrNoProgress].
+func ReadAll(r aio.SampleReader) ([]float32, error) {
+	var out []float32
+	buf := make([]float32, 4096)
+	for {
+		n, err := r.ReadSamples(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, io.ErrNoProgress
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package dsp_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/stretchr/testify/assert"
+)
+
+type stallingReader struct{}
+
+func (stallingReader) ReadSamples(p []float32) (int, error) {
+	return 0, nil
+}
+
+type failingReader struct{}
+
+func (failingReader) ReadSamples(p []float32) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestReadAll(t *testing.T) {
+	in := make([]float32, 10000)
+	for i := range in {
+		in[i] = float32(i)
+	}
+
+	out, err := dsp.ReadAll(dsp.NewSliceReader(in))
+	assert.NoError(t, err)
+	assert.Equal(t, in, out)
+
+	out, err = dsp.ReadAll(dsp.NewSliceReader(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestReadAll_Errors(t *testing.T) {
+	_, err := dsp.ReadAll(stallingReader{})
+	assert.ErrorIs(t, err, io.ErrNoProgress)
+
+	_, err = dsp.ReadAll(failingReader{})
+	assert.EqualError(t, err, "read failed")
+}
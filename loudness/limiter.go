@@ -0,0 +1,226 @@
+package loudness
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+const (
+	// DefaultLimiterLookahead is the lookahead of a [Limiter].
+	DefaultLimiterLookahead = 5 * time.Millisecond
+
+	// DefaultLimiterRelease is the release time of a [Limiter] by default.
+	DefaultLimiterRelease = 100 * time.Millisecond
+)
+
+const (
+	truePeakPhases = 4  // oversampling factor of the true peak estimation
+	truePeakTaps   = 12 // taps of the interpolation filter per phase
+)
+
+// truePeakFilter holds the interpolation filter of each oversampled phase between two samples.
+var truePeakFilter = func() (f [truePeakPhases - 1][truePeakTaps]float64) {
+	for ph := range f {
+		frac := float64(ph+1) / truePeakPhases
+		var sum float64
+		for j := range truePeakTaps {
+			t := frac - float64(j-truePeakTaps/2+1)
+			w := 0.42 + 0.5*math.Cos(math.Pi*t/(truePeakTaps/2)) + 0.08*math.Cos(2*math.Pi*t/(truePeakTaps/2)) // Blackman
+			f[ph][j] = sinc(t) * w
+			sum += f[ph][j]
+		}
+		for j := range f[ph] {
+			f[ph][j] /= sum
+		}
+	}
+	return f
+}()
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Limiter is an [aio.SampleReader] that limits the true peak level of the interleaved audio
+// read from its source, so it doesn't clip (even after conversion to integer samples or lossy encoding).
+//
+// The true peak is estimated by 4x oversampling. The gain is reduced smoothly ahead of the peaks
+// (see [DefaultLimiterLookahead]) and recovers with the release time. The output is aligned with the source
+// and has the same length, but the Limiter reads ahead of its output by its latency. The source must return whole frames.
+type Limiter struct {
+	src      aio.SampleReader
+	channels int
+	ceiling  float64 // linear
+	release  float64 // release coefficient per frame
+	sr       float64
+
+	lookahead int       // in frames
+	delay     int       // latency in frames
+	hist      []float32 // last truePeakTaps frames of the input, oldest first
+	minIdx    []int     // sliding minimum of the required gain (indices and values)
+	minVal    []float64
+	gain      float64   // gain after the release
+	box       []float64 // last lookahead gains for smoothing the attack
+	boxSum    float64
+	line      []float32 // delay line of delay+1 frames
+	pos       int       // number of input frames processed
+
+	buf     []float32
+	flushed int // number of silent frames left to push through the delay line after the end of the source
+	eof     bool
+}
+
+// NewLimiter creates a new [Limiter] that limits src (in the format) to the ceiling in dBTP (e.g. -1).
+func NewLimiter(src aio.SampleReader, format afmt.Format, ceiling float64) *Limiter {
+	sr := format.SampleRate.Hertz()
+	ch := format.NumChannels
+	lookahead := max(int(math.Round(DefaultLimiterLookahead.Seconds()*sr)), 1)
+	delay := lookahead - 1 + truePeakTaps/2
+
+	l := &Limiter{
+		src:       src,
+		channels:  ch,
+		ceiling:   dsp.DBToGain(ceiling),
+		sr:        sr,
+		lookahead: lookahead,
+		delay:     delay,
+		hist:      make([]float32, truePeakTaps*ch),
+		gain:      1,
+		box:       make([]float64, lookahead),
+		boxSum:    float64(lookahead),
+		line:      make([]float32, (delay+1)*ch),
+	}
+	for i := range l.box {
+		l.box[i] = 1
+	}
+	l.SetRelease(DefaultLimiterRelease)
+	return l
+}
+
+// SetRelease sets the time it takes the gain to recover after a peak.
+func (l *Limiter) SetRelease(d time.Duration) {
+	l.release = math.Exp(-1 / (d.Seconds() * l.sr))
+}
+
+// Latency returns how far the Limiter reads ahead of its output (the lookahead plus the delay of the true peak estimation).
+func (l *Limiter) Latency() time.Duration {
+	return time.Duration(float64(l.delay) / l.sr * float64(time.Second))
+}
+
+func (l *Limiter) ReadSamples(p []float32) (int, error) {
+	ch := l.channels
+	frames := len(p) / ch
+	if frames == 0 {
+		return 0, nil
+	}
+
+	n := 0
+	if !l.eof {
+		if cap(l.buf) < frames*ch {
+			l.buf = make([]float32, frames*ch)
+		}
+		in := l.buf[:frames*ch]
+
+		read, err := l.src.ReadSamples(in)
+		read -= read % ch
+		for i := 0; i < read; i += ch {
+			if l.process(in[i:i+ch], p[n:n+ch]) {
+				n += ch
+			}
+		}
+
+		if err == io.EOF {
+			l.eof = true
+			if l.pos > 0 {
+				l.flushed = l.delay
+			}
+		} else if err != nil {
+			return n, err
+		}
+	}
+
+	if l.eof {
+		zero := make([]float32, ch)
+		for ; l.flushed > 0 && n < frames*ch; l.flushed-- {
+			if l.process(zero, p[n:n+ch]) {
+				n += ch
+			}
+		}
+		if l.flushed == 0 {
+			return n, io.EOF
+		}
+	}
+	return n, nil
+}
+
+// process adds the input frame and writes the delayed output frame to out.
+// It reports whether an output frame was written (i.e. the delay line is full).
+func (l *Limiter) process(in, out []float32) bool {
+	ch := l.channels
+	i := l.pos
+	l.pos++
+
+	// true peak of the frame in the middle of the history
+	copy(l.hist, l.hist[ch:])
+	copy(l.hist[len(l.hist)-ch:], in)
+	peak := 0.0
+	for c := range ch {
+		peak = max(peak, math.Abs(float64(l.hist[(truePeakTaps/2-1)*ch+c])))
+		for _, f := range truePeakFilter {
+			var y float64
+			for j, coef := range f {
+				y += float64(l.hist[j*ch+c]) * coef
+			}
+			peak = max(peak, math.Abs(y))
+		}
+	}
+	required := 1.0
+	if peak > l.ceiling {
+		required = l.ceiling / peak
+	}
+
+	// minimum of the required gain over the lookahead
+	for len(l.minVal) > 0 && l.minVal[len(l.minVal)-1] >= required {
+		l.minIdx = l.minIdx[:len(l.minIdx)-1]
+		l.minVal = l.minVal[:len(l.minVal)-1]
+	}
+	l.minIdx = append(l.minIdx, i)
+	l.minVal = append(l.minVal, required)
+	if l.minIdx[0] <= i-l.lookahead {
+		l.minIdx = l.minIdx[1:]
+		l.minVal = l.minVal[1:]
+	}
+	target := l.minVal[0]
+
+	// instant attack (smoothed below), exponential release
+	if target < l.gain {
+		l.gain = target
+	} else {
+		l.gain = target + (l.gain-target)*l.release
+	}
+
+	// averaging over the lookahead turns the attack into a ramp that reaches the required gain at the peak
+	b := i % l.lookahead
+	l.boxSum += l.gain - l.box[b]
+	l.box[b] = l.gain
+	gain := float32(l.boxSum / float64(l.lookahead))
+
+	// delay line
+	size := l.delay + 1
+	copy(l.line[(i%size)*ch:], in)
+	if i < l.delay {
+		return false
+	}
+	delayed := l.line[((i+1)%size)*ch:][:ch]
+	for c := range ch {
+		out[c] = delayed[c] * gain
+	}
+	return true
+}
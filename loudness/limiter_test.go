@@ -0,0 +1,35 @@
+package loudness_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/loudness"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	in := sine(2, 1)
+	l := loudness.NewLimiter(dsp.NewSliceReader(in), mono48k, -1)
+
+	out := readAll(t, l)
+	assert.Len(t, out, len(in))
+
+	ceiling := math.Pow(10, -1.0/20)
+	var peak float64
+	for _, v := range out {
+		peak = max(peak, math.Abs(float64(v)))
+	}
+	assert.LessOrEqual(t, peak, ceiling+1e-3)
+	assert.Greater(t, peak, ceiling-0.05)
+}
+
+func TestLimiter_BelowCeiling(t *testing.T) {
+	in := sine(0.5, 1)
+	l := loudness.NewLimiter(dsp.NewSliceReader(in), mono48k, -1)
+
+	out := readAll(t, l)
+	assert.Len(t, out, len(in))
+	assert.InDeltaSlice(t, in, out, 1e-6)
+}
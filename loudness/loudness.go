@@ -0,0 +1,187 @@
+// Package loudness implements loudness measurement (ITU-R BS.1770 / EBU R128), loudness normalization
+// and a true-peak limiter for the rendered output.
+package loudness
+
+import (
+	"math"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+const (
+	absoluteGate = -70 // LUFS
+	relativeGate = -10 // LU below the ungated loudness
+)
+
+// Meter measures the integrated loudness of interleaved audio as specified by EBU R128
+// (K-weighting, 400 ms blocks with 75% overlap, absolute and relative gating).
+//
+// All channels are weighted equally, which is correct for mono and stereo.
+type Meter struct {
+	channels int
+	filters  []kWeighting // per channel
+
+	step   int       // samples per 100 ms step (per channel)
+	n      int       // samples in the current step
+	energy float64   // energy of the current step
+	steps  []float64 // energy of the last 3 steps
+	powers []float64 // mean square of every 400 ms block
+}
+
+// NewMeter creates a new [Meter] for audio in the format.
+func NewMeter(format afmt.Format) *Meter {
+	sr := format.SampleRate.Hertz()
+	m := &Meter{
+		channels: format.NumChannels,
+		filters:  make([]kWeighting, format.NumChannels),
+		step:     max(int(math.Round(sr/10)), 1),
+	}
+	for i := range m.filters {
+		m.filters[i] = newKWeighting(sr)
+	}
+	return m
+}
+
+// Write measures the interleaved samples.
+func (m *Meter) Write(p []float32) {
+	for i := 0; i+m.channels <= len(p); i += m.channels {
+		for ch := range m.channels {
+			y := m.filters[ch].process(float64(p[i+ch]))
+			m.energy += y * y
+		}
+
+		m.n++
+		if m.n < m.step {
+			continue
+		}
+
+		// a block is the current step and the 3 before it
+		if len(m.steps) == 3 {
+			block := m.energy
+			for _, e := range m.steps {
+				block += e
+			}
+			m.powers = append(m.powers, block/float64(4*m.step))
+			m.steps = m.steps[1:]
+		}
+		m.steps = append(m.steps, m.energy)
+		m.energy = 0
+		m.n = 0
+	}
+}
+
+// Integrated returns the integrated loudness of the measured audio in LUFS.
+// It returns -Inf if the audio is silent or shorter than 400 ms.
+func (m *Meter) Integrated() float64 {
+	mean := func(threshold float64) float64 {
+		var sum float64
+		var n int
+		for _, p := range m.powers {
+			if powerToLUFS(p) > threshold {
+				sum += p
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+
+	ungated := mean(absoluteGate)
+	if ungated == 0 {
+		return math.Inf(-1)
+	}
+	return powerToLUFS(mean(powerToLUFS(ungated) + relativeGate))
+}
+
+func powerToLUFS(p float64) float64 {
+	return -0.691 + 10*math.Log10(p)
+}
+
+// Measure returns the integrated loudness of the interleaved samples in LUFS.
+func Measure(samples []float32, format afmt.Format) float64 {
+	m := NewMeter(format)
+	m.Write(samples)
+	return m.Integrated()
+}
+
+// Normalize is the two-pass offline loudness normalization.
+//
+// It reads all samples from r and measures their integrated loudness, then returns a reader
+// over the samples with the gain applied so that they reach the target loudness in LUFS
+// (e.g. -23 for EBU R128 broadcast or -14 for streaming services). Silent audio is returned as is.
+//
+// Raising the loudness can push the peaks above full scale, so the result is usually passed through a [Limiter].
+func Normalize(r aio.SampleReader, format afmt.Format, target float64) (aio.SampleReader, error) {
+	samples, err := dsp.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	loudness := Measure(samples, format)
+	if math.IsInf(loudness, -1) {
+		return dsp.NewSliceReader(samples), nil
+	}
+
+	gain := float32(dsp.DBToGain(target - loudness))
+	for i := range samples {
+		samples[i] *= gain
+	}
+	return dsp.NewSliceReader(samples), nil
+}
+
+// kWeighting is the K-weighting filter of BS.1770, a high shelf followed by a high pass.
+// The coefficients are derived for any sample rate (they match the standard at 48 kHz).
+type kWeighting struct {
+	shelf, highPass dsp.Biquad
+}
+
+func newKWeighting(sr float64) kWeighting {
+	var k kWeighting
+
+	// high shelf
+	{
+		const (
+			f0   = 1681.974450955533
+			gain = 3.999843853973347
+			q    = 0.7071752369554196
+		)
+		K := math.Tan(math.Pi * f0 / sr)
+		vh := math.Pow(10, gain/20)
+		vb := math.Pow(vh, 0.4996667741545416)
+		a0 := 1 + K/q + K*K
+		k.shelf = dsp.Biquad{
+			B0: (vh + vb*K/q + K*K) / a0,
+			B1: 2 * (K*K - vh) / a0,
+			B2: (vh - vb*K/q + K*K) / a0,
+			A1: 2 * (K*K - 1) / a0,
+			A2: (1 - K/q + K*K) / a0,
+		}
+	}
+
+	// high pass
+	{
+		const (
+			f0 = 38.13547087602444
+			q  = 0.5003270373238773
+		)
+		K := math.Tan(math.Pi * f0 / sr)
+		a0 := 1 + K/q + K*K
+		k.highPass = dsp.Biquad{
+			B0: 1,
+			B1: -2,
+			B2: 1,
+			A1: 2 * (K*K - 1) / a0,
+			A2: (1 - K/q + K*K) / a0,
+		}
+	}
+
+	return k
+}
+
+func (k *kWeighting) process(x float64) float64 {
+	return k.highPass.Process(k.shelf.Process(x))
+}
@@ -0,0 +1,60 @@
+package loudness_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/loudness"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/SladkyCitron/resona/freq"
+	"github.com/stretchr/testify/assert"
+)
+
+var mono48k = afmt.Format{SampleRate: 48000 * freq.Hertz, NumChannels: 1}
+
+func sine(amp float64, seconds float64) []float32 {
+	out := make([]float32, int(seconds*48000))
+	for i := range out {
+		out[i] = float32(amp * math.Sin(2*math.Pi*997*float64(i)/48000))
+	}
+	return out
+}
+
+func readAll(t *testing.T, r aio.SampleReader) []float32 {
+	t.Helper()
+	out, err := dsp.ReadAll(r)
+	assert.NoError(t, err)
+	return out
+}
+
+func TestMeasure(t *testing.T) {
+	// a full scale 997 Hz sine in one channel is -3.01 LUFS
+	assert.InDelta(t, -3.01, loudness.Measure(sine(1, 5), mono48k), 0.05)
+	assert.InDelta(t, -23.01, loudness.Measure(sine(0.1, 5), mono48k), 0.05)
+}
+
+func TestMeasure_Stereo(t *testing.T) {
+	s := sine(1, 5)
+	stereo := make([]float32, 2*len(s))
+	for i, v := range s {
+		stereo[2*i] = v
+	}
+
+	got := loudness.Measure(stereo, afmt.Format{SampleRate: 48000 * freq.Hertz, NumChannels: 2})
+	assert.InDelta(t, -3.01, got, 0.05)
+}
+
+func TestMeasure_Silence(t *testing.T) {
+	assert.True(t, math.IsInf(loudness.Measure(make([]float32, 48000), mono48k), -1))
+}
+
+func TestNormalize(t *testing.T) {
+	r, err := loudness.Normalize(dsp.NewSliceReader(sine(0.5, 5)), mono48k, -23)
+	assert.NoError(t, err)
+
+	out := readAll(t, r)
+	assert.Len(t, out, 5*48000)
+	assert.InDelta(t, -23, loudness.Measure(out, mono48k), 0.05)
+}
@@ -0,0 +1,185 @@
+package gotau
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/SladkyCitron/gotau/effect"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// MixerTrack represents a single track of the [Mixer].
+//
+// The mixing parameters may be changed between ReadSamples calls of the Mixer.
+type MixerTrack struct {
+	// Source is the mono audio source of the track, usually a [Synth].
+	Source aio.SampleReader
+
+	// Volume is the volume of the track in decibels (dB), where 0 is the original volume.
+	Volume float64
+
+	// Pan is the stereo position of the track from -1 (left) to 1 (right), where 0 is the center.
+	// It's ignored by mono mixers.
+	Pan float64
+
+	// Mute specifies whether the track is muted.
+	Mute bool
+
+	// Solo specifies whether the track is soloed. If any track is soloed, only soloed tracks are heard.
+	Solo bool
+
+	buf  []float32
+	done bool
+
+	// gain is the linear gain of gainVolume, so it's only recomputed when Volume changes
+	gain       float32
+	gainVolume float64
+	gainSet    bool
+}
+
+// linearGain returns the linear gain of the volume of the track.
+func (t *MixerTrack) linearGain() float32 {
+	if !t.gainSet || t.gainVolume != t.Volume {
+		t.gain = float32(dsp.DBToGain(t.Volume))
+		t.gainVolume = t.Volume
+		t.gainSet = true
+	}
+	return t.gain
+}
+
+// NewMixerTrack creates a new [MixerTrack] that plays src with the mixing parameters of the track.
+func NewMixerTrack(src aio.SampleReader, track sequence.Track) *MixerTrack {
+	return &MixerTrack{
+		Source: src,
+		Volume: track.Volume,
+		Pan:    track.Pan,
+		Mute:   track.Mute,
+		Solo:   track.Solo,
+	}
+}
+
+// Mixer is an [aio.SampleReader] that sums multiple tracks into a mono or stereo output.
+// A [sequence.Project] is usually rendered with [NewProjectMixer].
+//
+// All sources must have the same sample rate. Muted tracks are still read, so they stay
+// in sync when they're unmuted. The output ends when all sources have ended.
+//
+// If a source fails, ReadSamples returns the samples mixed so far together with the error,
+// and the track is silent from then on.
+type Mixer struct {
+	tracks   []*MixerTrack
+	channels int
+}
+
+// NewMixer creates a new [Mixer] with the given number of output channels (1 for mono, 2 for stereo) and tracks.
+// Stereo output is interleaved.
+func NewMixer(channels int, tracks ...*MixerTrack) (*Mixer, error) {
+	if channels != 1 && channels != 2 {
+		return nil, fmt.Errorf("gotau Mixer: unsupported number of channels: %d", channels)
+	}
+	return &Mixer{tracks: slices.Clone(tracks), channels: channels}, nil
+}
+
+// NewProjectMixer creates a [Mixer] that renders all tracks of the project, with one [Synth] per track.
+//
+// newSynth creates the Synth of the track with index i. Voicebanks and phonemizers are up to
+// the application, so it usually picks them by [sequence.Track.VoicebankPath] and [sequence.Track.Phonemizer].
+// The track's sequence (see [sequence.Project.Sequence]) is enqueued into the Synth, whose output
+// goes through the track's effects (see [effect.FromTrack]) and is played with the track's mixing
+// parameters. All Synths must have the same sample rate.
+func NewProjectMixer(proj sequence.Project, channels int, newSynth func(i int, track sequence.Track) (*Synth, error)) (*Mixer, error) {
+	tracks := make([]*MixerTrack, 0, len(proj.Tracks))
+	for i, track := range proj.Tracks {
+		fx, err := effect.FromTrack(track)
+		if err != nil {
+			return nil, fmt.Errorf("gotau Mixer: invalid effects of track %d: %w", i, err)
+		}
+		synth, err := newSynth(i, track)
+		if err != nil {
+			return nil, fmt.Errorf("gotau Mixer: failed to create synth of track %d: %w", i, err)
+		}
+		synth.EnqueueSequence(proj.Sequence(i))
+		tracks = append(tracks, NewMixerTrack(fx.Apply(synth, synth.Format()), track))
+	}
+	return NewMixer(channels, tracks...)
+}
+
+// Tracks returns the tracks of the mixer.
+func (m *Mixer) Tracks() []*MixerTrack {
+	return m.tracks
+}
+
+func (m *Mixer) ReadSamples(p []float32) (int, error) {
+	frames := len(p) / m.channels
+	if frames == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.ErrShortBuffer
+	}
+	out := p[:frames*m.channels]
+	clear(out)
+
+	solo := slices.ContainsFunc(m.tracks, func(t *MixerTrack) bool { return t.Solo })
+
+	n := 0
+	var readErr error
+	for i, t := range m.tracks {
+		if t.done {
+			continue
+		}
+
+		if cap(t.buf) < frames {
+			t.buf = make([]float32, frames)
+		}
+		buf := t.buf[:frames]
+
+		nn, err := readFull(t.Source, buf)
+		if err != nil && readErr == nil {
+			readErr = fmt.Errorf("gotau Mixer: failed to read track %d: %w", i, err)
+		}
+		if nn < frames || err != nil {
+			// a failed track is mixed up to the error and then stays silent
+			t.done = true
+		}
+		n = max(n, nn)
+
+		if t.Mute || (solo && !t.Solo) {
+			continue
+		}
+
+		gain := t.linearGain()
+		if m.channels == 1 {
+			for j, v := range buf[:nn] {
+				out[j] += v * gain
+			}
+			continue
+		}
+
+		left, right := panGains(t.Pan)
+		for j, v := range buf[:nn] {
+			out[2*j] += v * gain * left
+			out[2*j+1] += v * gain * right
+		}
+	}
+
+	if readErr != nil {
+		return n * m.channels, readErr
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n * m.channels, nil
+}
+
+// panGains returns the gains of the left and right channel for the pan position.
+// It uses the constant-power pan law, normalized to unity gain at the center.
+func panGains(pan float64) (left, right float32) {
+	pan = min(max(pan, -1), 1)
+	angle := (pan + 1) * math.Pi / 4
+	return float32(math.Sqrt2 * math.Cos(angle)), float32(math.Sqrt2 * math.Sin(angle))
+}
@@ -0,0 +1,210 @@
+package gotau_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMixer_Mono(t *testing.T) {
+	a := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1, 1})}
+	b := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{0.5})}
+
+	m, err := gotau.NewMixer(1, a, b)
+	assert.NoError(t, err)
+
+	p := make([]float32, 4)
+	n, err := m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1.5, 1, 1}, p[:n])
+
+	_, err = m.ReadSamples(p)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestMixer_Stereo(t *testing.T) {
+	left := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1}), Pan: -1}
+	center := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1})}
+
+	m, err := gotau.NewMixer(2, left, center)
+	assert.NoError(t, err)
+
+	p := make([]float32, 4)
+	n, err := m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.InDeltaSlice(t, []float32{2.4142, 1, 2.4142, 1}, p, 1e-4)
+}
+
+func TestMixer_MuteSolo(t *testing.T) {
+	muted := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1}), Mute: true}
+	solo := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{0.5, 0.5}), Solo: true}
+	other := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{0.25, 0.25})}
+
+	m, err := gotau.NewMixer(1, muted, solo, other)
+	assert.NoError(t, err)
+
+	p := make([]float32, 1)
+	_, err = m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0.5}, p)
+
+	// muted tracks keep playing in the background
+	muted.Mute = false
+	solo.Solo = false
+	_, err = m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1.75}, p)
+}
+
+func TestMixer_Volume(t *testing.T) {
+	track := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1}), Volume: -20}
+
+	m, err := gotau.NewMixer(1, track)
+	assert.NoError(t, err)
+
+	p := make([]float32, 1)
+	_, err = m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.1, p[0], 1e-6)
+
+	// the volume may change between reads
+	track.Volume = 0
+	_, err = m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1}, p)
+}
+
+func TestNewMixer_InvalidChannels(t *testing.T) {
+	_, err := gotau.NewMixer(3)
+	assert.Error(t, err)
+}
+
+// failingReader returns its samples together with err.
+type failingReader struct {
+	s   []float32
+	err error
+}
+
+func (r *failingReader) ReadSamples(p []float32) (int, error) {
+	n := copy(p, r.s)
+	r.s = r.s[n:]
+	return n, r.err
+}
+
+func TestMixer_ReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+	failing := &gotau.MixerTrack{Source: &failingReader{s: []float32{0.5}, err: errRead}}
+	other := &gotau.MixerTrack{Source: dsp.NewSliceReader([]float32{1, 1, 1})}
+
+	m, err := gotau.NewMixer(1, failing, other)
+	assert.NoError(t, err)
+
+	p := make([]float32, 2)
+	n, err := m.ReadSamples(p)
+	assert.ErrorIs(t, err, errRead)
+	assert.Equal(t, []float32{1.5, 1}, p[:n])
+
+	// the failed track is silent from then on
+	n, err = m.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1}, p[:n])
+}
+
+func TestNewProjectMixer(t *testing.T) {
+	vb := openTestVoicebank(t)
+	proj := sequence.Project{
+		Metadata: testSeq.Metadata,
+		Tracks: []sequence.Track{
+			{Name: "lead", Notes: testSeq.Notes, Pan: -1},
+			{Name: "harmony", Notes: testSeq.Notes[1:], Volume: -6, Mute: true},
+		},
+	}
+
+	var names []string
+	m, err := gotau.NewProjectMixer(proj, 2, func(i int, track sequence.Track) (*gotau.Synth, error) {
+		names = append(names, track.Name)
+		return gotau.New(44100, vb, loopResampler{}, nil), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lead", "harmony"}, names)
+	assert.Equal(t, -6.0, m.Tracks()[1].Volume)
+
+	lead := gotau.New(44100, vb, loopResampler{}, nil)
+	lead.EnqueueSequence(testSeq)
+	want := renderAll(t, lead)
+
+	var got []float32
+	p := make([]float32, 2000)
+	for {
+		n, err := m.ReadSamples(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+	}
+	assert.Len(t, got, 2*len(want))
+	for i, v := range want {
+		// the lead is panned hard left and the harmony is muted
+		if !assert.InDelta(t, v*math.Sqrt2, got[2*i], 1e-5) || !assert.InDelta(t, 0, got[2*i+1], 1e-5) {
+			break
+		}
+	}
+}
+
+func TestNewProjectMixer_Effects(t *testing.T) {
+	vb := openTestVoicebank(t)
+	proj := sequence.Project{
+		Metadata: testSeq.Metadata,
+		Tracks: []sequence.Track{{
+			Name:  "lead",
+			Notes: testSeq.Notes,
+			Effects: []sequence.EffectConfig{
+				{Type: "compressor", Params: map[string]float64{"ratio": 1, "makeup": -6}},
+			},
+		}},
+	}
+
+	m, err := gotau.NewProjectMixer(proj, 1, func(i int, track sequence.Track) (*gotau.Synth, error) {
+		return gotau.New(44100, vb, loopResampler{}, nil), nil
+	})
+	assert.NoError(t, err)
+	got, err := dsp.ReadAll(m)
+	assert.NoError(t, err)
+
+	lead := gotau.New(44100, vb, loopResampler{}, nil)
+	lead.EnqueueSequence(testSeq)
+	want := renderAll(t, lead)
+
+	// the compressor only applies its makeup gain
+	gain := float32(math.Pow(10, -6.0/20))
+	assert.Len(t, got, len(want))
+	for i, v := range want {
+		if !assert.InDelta(t, v*gain, got[i], 1e-5) {
+			break
+		}
+	}
+
+	proj.Tracks[0].Effects = []sequence.EffectConfig{{Type: "flanger"}}
+	_, err = gotau.NewProjectMixer(proj, 1, func(i int, track sequence.Track) (*gotau.Synth, error) {
+		return gotau.New(44100, vb, loopResampler{}, nil), nil
+	})
+	assert.ErrorContains(t, err, "unknown effect type")
+}
+
+func TestNewProjectMixer_Error(t *testing.T) {
+	proj := sequence.Project{Tracks: []sequence.Track{{Name: "lead"}}}
+	errSynth := errors.New("no voicebank")
+
+	_, err := gotau.NewProjectMixer(proj, 2, func(i int, track sequence.Track) (*gotau.Synth, error) {
+		return nil, errSynth
+	})
+	assert.ErrorIs(t, err, errSynth)
+}
@@ -0,0 +1,200 @@
+package gotau
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/aio"
+)
+
+// renderJob is a planned note waiting to be (or being) resampled.
+type renderJob struct {
+	note        sequence.Note
+	otoEntry    voicebank.OtoEntry
+	resampleCfg resample.ResampleConfig
+	concatCfg   concat.Config
+	preutter    float64 // preutterance in milliseconds (after autofit)
+	overlap     float64 // overlap in milliseconds (after autofit)
+	start       int     // output position (in samples) where the note's sample starts; silent notes pad the output up to it
+	length      int     // number of samples to read from the resampler
+	silent      bool    // whether the note has nothing to concatenate
+	breath      bool    // whether the job is a breath inserted before the note
+
+	samples  []float32     // written by the worker
+	cacheHit bool          // written by the worker
+	err      error         // written by the worker
+	done     chan struct{} // closed when samples and err are ready
+}
+
+// fillPending plans upcoming notes from the queue and starts resampling them
+// until the number of pending jobs reaches the concurrency limit.
+//
+// Planning always happens in queue order on the caller's goroutine, so the
+// timing and lyric context are the same as in serial rendering.
+func (s *Synth) fillPending() {
+	for len(s.pending) < s.concurrency {
+		note, ok := s.sched.pop()
+		if !ok {
+			return
+		}
+
+		for _, job := range s.planNotes(note) {
+			s.pending = append(s.pending, job)
+
+			select {
+			case <-job.done:
+				continue // nothing to resample
+			default:
+			}
+
+			if s.concurrency <= 1 {
+				s.runJob(s.ctx, job)
+			} else {
+				go s.runJob(s.ctx, job)
+			}
+		}
+	}
+}
+
+func (s *Synth) runJob(ctx context.Context, job *renderJob) {
+	defer close(job.done)
+	job.err = s.resampleNote(ctx, job)
+}
+
+// concatenate pads the output with silence up to the start of the job and
+// concatenates the resampled note into the internal buffer.
+//
+// The note overlaps everything that has been concatenated after its start, so adjacent,
+// overlapping and nested notes are all mixed at their absolute positions.
+func (s *Synth) concatenate(job *renderJob) error {
+	s.pad(job.start)
+
+	if job.err != nil {
+		return job.err
+	}
+	if job.silent {
+		return nil
+	}
+
+	cfg := job.concatCfg
+	start := job.start
+
+	// the part of the output before the buffer has already been read, so the note
+	// can't reach into it anymore (e.g. when it was enqueued too late); cut off its beginning
+	if bufStart := s.outPos - len(s.buf); start < bufStart {
+		s.logger.Warn("note starts before the output that has already been read, cutting it off", "tick", job.note.Position, "lyric", job.note.Lyric)
+		cut := s.samplesToMs(bufStart - start)
+		cfg.Offset += cut
+		cfg.Length = max(cfg.Length-cut, 0)
+		start = bufStart
+	}
+	cfg.Overlap = s.samplesToMs(s.outPos - start)
+
+	before := len(s.buf)
+	buf, err := s.cat.Concatenate(s.ctx, s.buf, job.samples, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to concatenate: %w", err)
+	}
+	s.outPos += len(buf) - before
+	s.buf = buf
+	return nil
+}
+
+// pad pads the output with silence up to the position pos (in samples).
+//
+// Silence before the render range would be dropped by drain right away, so as long as
+// nothing is buffered, it's only counted instead of being allocated (e.g. after seeking).
+func (s *Synth) pad(pos int) {
+	n := pos - s.outPos
+	if n <= 0 {
+		return
+	}
+	if len(s.buf) == 0 {
+		skipped := min(n, s.skip)
+		s.skip -= skipped
+		s.outPos += skipped
+		n -= skipped
+	}
+
+	l := len(s.buf)
+	s.buf = slices.Grow(s.buf, n)[:l+n]
+	clear(s.buf[l:])
+	s.outPos += n
+}
+
+// updateReserve reserves the tail of the output that the pending notes can still overlap,
+// so it's not drained before they are concatenated.
+func (s *Synth) updateReserve() {
+	s.reserve = 0
+	for _, job := range s.pending {
+		if !job.silent {
+			s.reserve = max(s.reserve, s.outPos-job.start)
+		}
+	}
+}
+
+// reportProgress updates the progress after the job has been concatenated and reports it.
+func (s *Synth) reportProgress(job *renderJob) {
+	if !job.breath {
+		s.progress.NotesRendered++
+	}
+	if !job.silent {
+		if job.cacheHit {
+			s.progress.CacheHits++
+		} else {
+			s.progress.CacheMisses++
+		}
+	}
+
+	if s.progressFn != nil {
+		s.progressFn(s.Progress())
+	}
+}
+
+// drain copies samples from the internal buffer into p, leaving the reserved tail untouched.
+// Samples outside of the render range are dropped.
+func (s *Synth) drain(p []float32) int {
+	avail := max(len(s.buf)-s.reserve, 0)
+
+	if s.skip > 0 {
+		d := min(s.skip, avail)
+		s.buf = s.buf[d:]
+		s.skip -= d
+		avail -= d
+	}
+	if s.remaining >= 0 {
+		avail = min(avail, s.remaining)
+	}
+
+	n := copy(p, s.buf[:avail])
+	s.buf = s.buf[n:]
+	if s.remaining > 0 {
+		s.remaining -= n
+	}
+	return n
+}
+
+// readFull reads from r until p is full or r returns [io.EOF].
+func readFull(r aio.SampleReader, p []float32) (int, error) {
+	n := 0
+	for n < len(p) {
+		nn, err := r.ReadSamples(p[n:])
+		n += nn
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if nn == 0 {
+			return n, io.ErrNoProgress
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package pitch
+
+import (
+	"math"
+
+	"github.com/SladkyCitron/gotau/internal/timeutil"
+	"github.com/SladkyCitron/gotau/sequence"
+)
+
+// DefaultAutoPitch is an [AutoPitch] with a short portamento and a subtle overshoot,
+// similar to the default pitch bend of UTAU's Mode2.
+var DefaultAutoPitch = AutoPitch{
+	PortamentoStart:  -25,
+	PortamentoLength: 50,
+	Overshoot:        15,
+	OvershootLength:  60,
+}
+
+// AutoPitch generates natural pitch transitions for notes without pitch bend data.
+//
+// The transition goes from the pitch of the previous note to the pitch of the note.
+// It can be preceded by a preparation (a small dip in the opposite direction) and followed
+// by an overshoot past the target pitch. A slow drift can be added to the rest of the note.
+// The zero value only generates a 0 ms portamento, i.e. a flat pitch.
+type AutoPitch struct {
+	// PortamentoStart is the start of the transition in milliseconds, relative to the start of the note.
+	// Negative values start the transition before the note.
+	PortamentoStart float64
+
+	// PortamentoLength is the length of the transition in milliseconds.
+	PortamentoLength float64
+
+	// Overshoot is how far the pitch overshoots the target pitch at the end of the transition, in cents.
+	Overshoot float64
+
+	// OvershootLength is the time the pitch takes to settle after an overshoot, in milliseconds.
+	OvershootLength float64
+
+	// Preparation is how far the pitch dips in the opposite direction before the transition, in cents.
+	Preparation float64
+
+	// PreparationLength is the length of the preparation in milliseconds.
+	// If it's 0, a quarter of the portamento length is used.
+	PreparationLength float64
+
+	// Drift is the amplitude of the slow pitch drift after the transition, in cents. If it's 0, there's no drift.
+	Drift float64
+
+	// DriftPeriod is the period of the drift in milliseconds. If it's 0, 1000 ms is used.
+	DriftPeriod float64
+}
+
+// Generate returns the pitch curve of the note.
+//
+// The previous note is nil if the note doesn't directly follow a sung note, in which case
+// there's no transition. The resampled sample starts lead milliseconds before the note and is
+// length milliseconds long; the curve covers all of it and is in the format expected by
+// [EncodeResamplerPitchBendString] (ticks from the start of the sample at the tempo, absolute
+// pitch in cents).
+//
+// It returns nil if the pitch is flat, which renders exactly like a note without pitch bend.
+func (a AutoPitch) Generate(prev *sequence.Note, note sequence.Note, lead, length float64, tempo float64, tpqn int) sequence.Curve {
+	target := float64(note.Note) * 100
+	from := target
+	if prev != nil {
+		from = float64(prev.Note) * 100
+	}
+
+	// points in milliseconds relative to the start of the note
+	type point struct {
+		t, y float64
+	}
+	begin := -lead
+	end := length - lead
+	points := []point{{begin, from}}
+
+	t := max(a.PortamentoStart, begin)
+	if from != target {
+		dir := math.Copysign(1, target-from)
+
+		if a.Preparation != 0 {
+			prepLength := a.PreparationLength
+			if prepLength == 0 {
+				prepLength = a.PortamentoLength / 4
+			}
+			points = append(points, point{t, from})
+			t += prepLength
+			points = append(points, point{t, from - dir*a.Preparation})
+		} else {
+			points = append(points, point{t, from})
+		}
+
+		t += a.PortamentoLength
+		if a.Overshoot != 0 {
+			points = append(points, point{t, target + dir*a.Overshoot})
+			t += a.OvershootLength
+		}
+		points = append(points, point{t, target})
+	}
+
+	if a.Drift != 0 {
+		period := a.DriftPeriod
+		if period == 0 {
+			period = 1000
+		}
+		t = max(t, 0)
+		if len(points) == 1 {
+			points = append(points, point{t, target})
+		}
+		// alternate between the peaks of the drift, starting upwards
+		sign := 1.0
+		for t += period / 4; t < end; t += period / 2 {
+			points = append(points, point{t, target + sign*a.Drift})
+			sign = -sign
+		}
+	}
+
+	if len(points) == 1 {
+		return nil // flat
+	}
+	points = append(points, point{max(end, points[len(points)-1].t), target})
+
+	toTicks := func(ms float64) int {
+		return timeutil.SecondsToTicks((ms+lead)/1000, tpqn, tempo)
+	}
+
+	curve := make(sequence.Curve, 0, len(points)+1)
+	for _, p := range points {
+		x := toTicks(p.t)
+		if n := len(curve); n > 0 && curve[n-1].X >= x {
+			// too close to the previous point at this resolution; keep the later one
+			curve[n-1].Y = p.y
+			continue
+		}
+		curve = append(curve, sequence.CurvePoint{X: x, Y: p.y, Interp: sequence.CurveInterpolationSine})
+	}
+	// cover the rounding at the end of the sample
+	last := curve[len(curve)-1]
+	curve = append(curve, sequence.CurvePoint{X: last.X + 1, Y: last.Y})
+	return curve
+}
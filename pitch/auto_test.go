@@ -0,0 +1,59 @@
+package pitch_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/pitch"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoPitch_Generate(t *testing.T) {
+	ap := pitch.AutoPitch{PortamentoStart: -50, PortamentoLength: 100}
+	prev := sequence.Note{Note: 60}
+	note := sequence.Note{Note: 62}
+
+	// 125 BPM at 480 TPQN is 1 tick per ms
+	curve := ap.Generate(&prev, note, 100, 500, 125, 480)
+
+	assert.Equal(t, 6000.0, curve.At(0))
+	assert.Equal(t, 6000.0, curve.At(50))
+	assert.Equal(t, 6100.0, curve.At(100)) // halfway through the transition at the start of the note
+	assert.Equal(t, 6200.0, curve.At(150))
+	assert.Equal(t, 6200.0, curve.At(500))
+
+	s := pitch.EncodeResamplerPitchBendString(curve, note.Note, 0.5, 125, 480)
+	assert.NotEqual(t, "AA", s)
+}
+
+func TestAutoPitch_Generate_Overshoot(t *testing.T) {
+	ap := pitch.AutoPitch{PortamentoLength: 100, Overshoot: 20, OvershootLength: 50, Preparation: 10, PreparationLength: 20}
+	prev := sequence.Note{Note: 64}
+	note := sequence.Note{Note: 60}
+
+	curve := ap.Generate(&prev, note, 0, 500, 125, 480)
+
+	assert.Equal(t, 6400.0, curve.At(0))
+	assert.Equal(t, 6410.0, curve.At(20))         // preparation goes up before going down
+	assert.InDelta(t, 5980.0, curve.At(120), 0.1) // overshoot below the target
+	assert.Equal(t, 6000.0, curve.At(170))
+}
+
+func TestAutoPitch_Generate_Flat(t *testing.T) {
+	prev := sequence.Note{Note: 60}
+	note := sequence.Note{Note: 60}
+
+	assert.Nil(t, pitch.DefaultAutoPitch.Generate(nil, note, 100, 500, 125, 480))
+	assert.Nil(t, pitch.DefaultAutoPitch.Generate(&prev, note, 100, 500, 125, 480))
+}
+
+func TestAutoPitch_Generate_Drift(t *testing.T) {
+	ap := pitch.AutoPitch{Drift: 5, DriftPeriod: 400}
+	note := sequence.Note{Note: 60}
+
+	curve := ap.Generate(nil, note, 0, 1000, 125, 480)
+
+	assert.InDelta(t, 6005.0, curve.At(100), 0.1)
+	assert.InDelta(t, 5995.0, curve.At(300), 0.1)
+	assert.Equal(t, 6000.0, curve.At(1000))
+}
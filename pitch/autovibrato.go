@@ -0,0 +1,119 @@
+package pitch
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/SladkyCitron/gotau/sequence"
+)
+
+// DefaultAutoVibrato is an [AutoVibrato] that adds a moderate vibrato after 300 ms to held notes.
+var DefaultAutoVibrato = AutoVibrato{
+	MinLength:  500,
+	Delay:      300,
+	Rate:       5.5,
+	Depth:      30,
+	FadeIn:     200,
+	FadeOut:    50,
+	Randomness: 0.1,
+}
+
+// AutoVibratoPresets holds [AutoVibrato] presets for common singing styles by name.
+var AutoVibratoPresets = map[string]AutoVibrato{
+	"default": DefaultAutoVibrato,
+	"subtle": {
+		MinLength:  600,
+		Delay:      400,
+		Rate:       5,
+		Depth:      15,
+		FadeIn:     300,
+		FadeOut:    50,
+		Randomness: 0.05,
+	},
+	"ballad": {
+		MinLength:  400,
+		Delay:      250,
+		Rate:       5,
+		Depth:      40,
+		FadeIn:     350,
+		FadeOut:    100,
+		Randomness: 0.15,
+	},
+	"opera": {
+		MinLength:  300,
+		Delay:      100,
+		Rate:       6,
+		Depth:      70,
+		FadeIn:     150,
+		FadeOut:    50,
+		Randomness: 0.1,
+	},
+}
+
+// AutoVibrato generates vibrato for held notes without explicit vibrato data.
+type AutoVibrato struct {
+	// MinLength is the minimum duration of a note in milliseconds to get a vibrato.
+	MinLength float64
+
+	// Delay is the time from the start of the note to the start of the vibrato in milliseconds.
+	Delay float64
+
+	// Rate is the number of cycles per second.
+	Rate float64
+
+	// Depth is the depth (amplitude) in cents.
+	Depth float64
+
+	// FadeIn is the length of the fade-in in milliseconds.
+	FadeIn float64
+
+	// FadeOut is the length of the fade-out in milliseconds.
+	FadeOut float64
+
+	// Randomness is how much the rate and the depth vary between notes (0 to 1, as a fraction of their values).
+	// The variation is derived from the note itself, so rendering the same note again gives the same vibrato
+	// (and hits the resampler cache).
+	Randomness float64
+
+	// ModulationDepth makes the depth follow the modulation of the note (in percent),
+	// so the vibrato can be controlled per note. Notes with zero modulation get no vibrato.
+	ModulationDepth bool
+}
+
+// Vibrato returns the vibrato of the note that is duration milliseconds long,
+// or nil if the note doesn't get a vibrato.
+func (a AutoVibrato) Vibrato(note sequence.Note, duration float64) *sequence.Vibrato {
+	length := duration - a.Delay
+	if duration < a.MinLength || length <= 0 || a.Rate <= 0 {
+		return nil
+	}
+
+	rate, depth := a.Rate, a.Depth
+	if a.ModulationDepth {
+		depth *= note.Modulation / 100
+	}
+	if a.Randomness > 0 {
+		r := noteRand(note)
+		rate *= 1 + a.Randomness*(2*r.Float64()-1)
+		depth *= 1 + a.Randomness*(2*r.Float64()-1)
+	}
+	if depth == 0 {
+		return nil
+	}
+
+	return &sequence.Vibrato{
+		Length:  length / duration * 100,
+		Period:  1000 / rate,
+		Depth:   depth,
+		FadeIn:  min(a.FadeIn/length*100, 100),
+		FadeOut: min(a.FadeOut/length*100, 100),
+	}
+}
+
+// noteRand returns a random number generator seeded by the note.
+func noteRand(note sequence.Note) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(note.Lyric))
+	seed := h.Sum64()
+	return rand.New(rand.NewPCG(seed, uint64(note.Position)<<8|uint64(note.Note)))
+}
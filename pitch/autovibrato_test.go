@@ -0,0 +1,39 @@
+package pitch_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/pitch"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoVibrato_Vibrato(t *testing.T) {
+	av := pitch.AutoVibrato{MinLength: 500, Delay: 300, Rate: 5, Depth: 30, FadeIn: 140, FadeOut: 70}
+	note := sequence.Note{Lyric: "a", Note: 60}
+
+	vbr := av.Vibrato(note, 1000)
+
+	assert.Equal(t, &sequence.Vibrato{Length: 70, Period: 200, Depth: 30, FadeIn: 20, FadeOut: 10}, vbr)
+	assert.Nil(t, av.Vibrato(note, 400)) // too short
+}
+
+func TestAutoVibrato_Vibrato_Modulation(t *testing.T) {
+	av := pitch.AutoVibrato{Delay: 300, Rate: 5, Depth: 30, ModulationDepth: true}
+
+	assert.Nil(t, av.Vibrato(sequence.Note{Note: 60}, 1000))
+	assert.Equal(t, 15.0, av.Vibrato(sequence.Note{Note: 60, Modulation: 50}, 1000).Depth)
+}
+
+func TestAutoVibrato_Vibrato_Randomness(t *testing.T) {
+	av := pitch.DefaultAutoVibrato
+	a := sequence.Note{Position: 0, Lyric: "a", Note: 60}
+	b := sequence.Note{Position: 960, Lyric: "a", Note: 60}
+
+	// deterministic per note, but different between notes
+	assert.Equal(t, av.Vibrato(a, 1000), av.Vibrato(a, 1000))
+	assert.NotEqual(t, av.Vibrato(a, 1000), av.Vibrato(b, 1000))
+
+	depth := av.Vibrato(a, 1000).Depth
+	assert.InDelta(t, av.Depth, depth, av.Depth*av.Randomness)
+}
@@ -0,0 +1,38 @@
+package pitch
+
+import (
+	"math"
+
+	"github.com/SladkyCitron/gotau/internal/timeutil"
+	"github.com/SladkyCitron/gotau/sequence"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// vibratoStep is the sampling interval of the vibrato in milliseconds, which matches the resampler pitch bend string.
+const vibratoStep = 5
+
+// ApplyVibrato returns the curve with the vibrato of the note added on top of it.
+//
+// The resampled sample starts lead milliseconds before the note and is length milliseconds long,
+// and the note is duration milliseconds long. Both curves are in the format expected by
+// [EncodeResamplerPitchBendString]; where the curve is undefined (e.g. if it's empty), the pitch of the note is used.
+func ApplyVibrato(curve sequence.Curve, vibrato sequence.Vibrato, note midi.Note, lead, duration, length float64, tempo float64, tpqn int) sequence.Curve {
+	out := make(sequence.Curve, 0, int(length/vibratoStep)+2)
+	for t := float64(0); t <= length+vibratoStep; t += vibratoStep {
+		x := timeutil.SecondsToTicks(t/1000, tpqn, tempo)
+		if n := len(out); n > 0 && out[n-1].X >= x {
+			continue
+		}
+
+		y := curve.At(x)
+		if math.IsNaN(y) {
+			y = float64(note) * 100
+		}
+		out = append(out, sequence.CurvePoint{
+			X:      x,
+			Y:      y + vibrato.At(t-lead, duration),
+			Interp: sequence.CurveInterpolationLinear,
+		})
+	}
+	return out
+}
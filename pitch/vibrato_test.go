@@ -0,0 +1,33 @@
+package pitch_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/pitch"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyVibrato(t *testing.T) {
+	vbr := sequence.Vibrato{Length: 50, Period: 100, Depth: 50}
+
+	// 125 BPM at 480 TPQN is 1 tick per ms; the sample starts 100 ms before the note
+	curve := pitch.ApplyVibrato(nil, vbr, 60, 100, 1000, 1100, 125, 480)
+
+	assert.InDelta(t, 6000, curve.At(100), 1e-9)
+	assert.InDelta(t, 6050, curve.At(625), 1e-9)
+	assert.InDelta(t, 5950, curve.At(675), 1e-9)
+	assert.InDelta(t, 6000, curve.At(1100), 1e-9)
+}
+
+func TestApplyVibrato_Curve(t *testing.T) {
+	vbr := sequence.Vibrato{Length: 100, Period: 100, Depth: 50}
+	curve := sequence.Curve{
+		{X: 0, Y: 6200, Interp: sequence.CurveInterpolationLinear},
+		{X: 1000, Y: 6200},
+	}
+
+	got := pitch.ApplyVibrato(curve, vbr, 60, 0, 1000, 1000, 125, 480)
+
+	assert.InDelta(t, 6250, got.At(25), 1e-9)
+}
@@ -0,0 +1,221 @@
+package gotau
+
+import (
+	"math"
+
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/pitch"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// RenderPlan represents how a sequence would be rendered. It is returned by [Synth.Plan]
+// and can be serialized to JSON for debugging timing problems.
+type RenderPlan struct {
+	// SampleRate is the sample rate of the output in Hz.
+	SampleRate int `json:"sampleRate"`
+
+	// Notes holds the plans of the notes in the order they are rendered.
+	Notes []NotePlan `json:"notes"`
+}
+
+// NotePlan represents how a single note would be rendered.
+type NotePlan struct {
+	// Position is the position of the note in MIDI ticks.
+	Position int `json:"position"`
+
+	// Duration is the duration of the note in MIDI ticks.
+	Duration int `json:"duration"`
+
+	// Lyric is the lyric of the note.
+	Lyric string `json:"lyric"`
+
+	// Alias is the resolved oto alias. It's empty if the lyric didn't resolve.
+	Alias string `json:"alias,omitempty"`
+
+	// SampleFile is the path of the voicebank sample file used by the note.
+	SampleFile string `json:"sampleFile,omitempty"`
+
+	// Breath specifies whether the note is a breath inserted before the note at Position. See [Synth.SetBreath].
+	Breath bool `json:"breath,omitempty"`
+
+	// Silent specifies whether the note is rendered as silence (e.g. because the lyric didn't resolve).
+	Silent bool `json:"silent,omitempty"`
+
+	// StartSample is the output position (in samples) where the note's sample starts.
+	StartSample int `json:"startSample"`
+
+	// EndSample is the output position (in samples) where the note's sample ends.
+	EndSample int `json:"endSample"`
+
+	// Preutterance is the preutterance in milliseconds (after autofit).
+	Preutterance float64 `json:"preutterance"`
+
+	// Overlap is the overlap in milliseconds (after autofit).
+	Overlap float64 `json:"overlap"`
+
+	// Encroachment is how far the note's sample reaches into the previous note in milliseconds,
+	// i.e. the preutterance minus the overlap.
+	Encroachment float64 `json:"encroachment"`
+
+	// ResampleConfig is the configuration that would be passed to the resampler. It's nil for silent notes.
+	ResampleConfig *ResamplePlan `json:"resampleConfig,omitempty"`
+
+	// PitchString is the pitch bend string that would be passed to an UTAU resampler.
+	PitchString string `json:"pitchString,omitempty"`
+
+	// ConcatConfig is the configuration that would be passed to the concatenator. It's nil for silent notes.
+	ConcatConfig *ConcatPlan `json:"concatConfig,omitempty"`
+}
+
+// ResamplePlan is the configuration that would be passed to the resampler, in the form of a [NotePlan].
+// The fields are the same as in [resample.ResampleConfig], with the audio format split into SampleRate and Channels.
+type ResamplePlan struct {
+	Pitch      midi.Note        `json:"pitch"`
+	Velocity   float64          `json:"velocity"`
+	Flags      string           `json:"flags,omitempty"`
+	Offset     float64          `json:"offset"`
+	Length     float64          `json:"length"`
+	Consonant  float64          `json:"consonant"`
+	Cutoff     float64          `json:"cutoff"`
+	Intensity  float64          `json:"intensity"`
+	Modulation float64          `json:"modulation"`
+	Tempo      float64          `json:"tempo"`
+	Resolution int              `json:"resolution"`
+	PitchBend  []CurvePointPlan `json:"pitchBend,omitempty"`
+	SampleRate int              `json:"sampleRate"`
+	Channels   int              `json:"channels"`
+}
+
+// CurvePointPlan is a point of a [sequence.Curve] in the form of a [NotePlan].
+type CurvePointPlan struct {
+	X      int                         `json:"x"`
+	Y      float64                     `json:"y"`
+	Interp sequence.CurveInterpolation `json:"interp"`
+}
+
+// ConcatPlan is the configuration that would be passed to the concatenator, in the form of a [NotePlan].
+// The fields are the same as in [concat.Config], with the audio format split into SampleRate and Channels.
+type ConcatPlan struct {
+	Offset     float64      `json:"offset"`
+	Length     float64      `json:"length"`
+	Envelope   EnvelopePlan `json:"envelope"`
+	Overlap    float64      `json:"overlap"`
+	Intensity  float64      `json:"intensity"`
+	SampleRate int          `json:"sampleRate"`
+	Channels   int          `json:"channels"`
+}
+
+// EnvelopePlan is a [sequence.Envelope] in the form of a [NotePlan].
+type EnvelopePlan struct {
+	P1 float64 `json:"p1"`
+	P2 float64 `json:"p2"`
+	P3 float64 `json:"p3"`
+	P4 float64 `json:"p4"`
+	P5 float64 `json:"p5"`
+	V1 float64 `json:"v1"`
+	V2 float64 `json:"v2"`
+	V3 float64 `json:"v3"`
+	V4 float64 `json:"v4"`
+	V5 float64 `json:"v5"`
+}
+
+func newResamplePlan(cfg resample.ResampleConfig) *ResamplePlan {
+	p := &ResamplePlan{
+		Pitch:      cfg.Pitch,
+		Velocity:   cfg.Velocity,
+		Flags:      cfg.Flags,
+		Offset:     cfg.Offset,
+		Length:     cfg.Length,
+		Consonant:  cfg.Consonant,
+		Cutoff:     cfg.Cutoff,
+		Intensity:  cfg.Intensity,
+		Modulation: cfg.Modulation,
+		Tempo:      cfg.Tempo,
+		Resolution: cfg.Resolution,
+		SampleRate: int(cfg.AudioFormat.SampleRate.Hertz()),
+		Channels:   cfg.AudioFormat.NumChannels,
+	}
+	for _, pt := range cfg.PitchBend {
+		p.PitchBend = append(p.PitchBend, CurvePointPlan{X: pt.X, Y: pt.Y, Interp: pt.Interp})
+	}
+	return p
+}
+
+func newConcatPlan(cfg concat.Config) *ConcatPlan {
+	e := cfg.Envelope
+	return &ConcatPlan{
+		Offset: cfg.Offset,
+		Length: cfg.Length,
+		Envelope: EnvelopePlan{
+			P1: e.P1, P2: e.P2, P3: e.P3, P4: e.P4, P5: e.P5,
+			V1: e.V1, V2: e.V2, V3: e.V3, V4: e.V4, V5: e.V5,
+		},
+		Overlap:    cfg.Overlap,
+		Intensity:  cfg.Intensity,
+		SampleRate: int(cfg.AudioFormat.SampleRate.Hertz()),
+		Channels:   cfg.AudioFormat.NumChannels,
+	}
+}
+
+// Plan computes how the sequence would be rendered by the Synth (with its voicebank, phonemizer
+// and sample rate) without invoking the resampler or the concatenator.
+// The BeforeResample functions of the hooks are applied.
+//
+// Plan doesn't modify the state of the Synth, so it can be called at any time.
+func (s *Synth) Plan(seq sequence.Sequence) RenderPlan {
+	p := New(s.sr, s.vb, s.res, s.cat)
+	p.ph = s.ph
+	p.hooks = s.hooks
+	p.autoPitch = s.autoPitch
+	p.autoVibrato = s.autoVibrato
+	p.breath = s.breath
+	p.EnqueueSequence(seq)
+
+	plan := RenderPlan{SampleRate: s.sr}
+	for {
+		note, ok := p.sched.pop()
+		if !ok {
+			break
+		}
+		for _, job := range p.planNotes(note) {
+			plan.Notes = append(plan.Notes, job.plan())
+		}
+	}
+	return plan
+}
+
+// plan returns the plan of the job.
+func (job *renderJob) plan() NotePlan {
+	np := NotePlan{
+		Position:     job.note.Position,
+		Duration:     job.note.Duration,
+		Lyric:        job.note.Lyric,
+		Alias:        job.otoEntry.Alias,
+		Breath:       job.breath,
+		Silent:       job.silent,
+		StartSample:  job.start,
+		EndSample:    job.start,
+		Preutterance: job.preutter,
+		Overlap:      job.overlap,
+		Encroachment: job.preutter - job.overlap,
+	}
+	if job.otoEntry.Filename != "" {
+		np.SampleFile = job.otoEntry.FilePath()
+	}
+	if job.silent {
+		return np
+	}
+
+	cfg := job.resampleCfg
+	concatCfg := job.concatCfg
+	sr := cfg.AudioFormat.SampleRate.Hertz()
+
+	np.StartSample = job.start
+	np.EndSample = np.StartSample + int(math.Round(concatCfg.Length*sr/1000))
+	np.ResampleConfig = newResamplePlan(cfg)
+	np.PitchString = pitch.EncodeResamplerPitchBendString(cfg.PitchBend, cfg.Pitch, cfg.Length/1000, cfg.Tempo, cfg.Resolution)
+	np.ConcatConfig = newConcatPlan(concatCfg)
+	return np
+}
@@ -0,0 +1,104 @@
+package gotau_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynth_Plan(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Directory: "cv", Alias: "a", Preutterance: 100, Overlap: 20},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+			{Position: 960, Duration: 480, Lyric: "xyz", Note: 60, Intensity: 1},
+		},
+	}
+
+	plan := synth.Plan(seq)
+	assert.Equal(t, 1000, plan.SampleRate)
+	assert.Len(t, plan.Notes, 2)
+
+	a := plan.Notes[0]
+	assert.Equal(t, "a", a.Alias)
+	assert.Equal(t, "cv/a.wav", a.SampleFile)
+	assert.False(t, a.Silent)
+	assert.Equal(t, 400, a.StartSample)
+	assert.Equal(t, 1000, a.EndSample)
+	assert.Equal(t, 100.0, a.Preutterance)
+	assert.Equal(t, 20.0, a.Overlap)
+	assert.Equal(t, 80.0, a.Encroachment)
+	assert.Equal(t, 650.0, a.ResampleConfig.Length)
+	assert.Equal(t, "AA", a.PitchString)
+	assert.Equal(t, 600.0, a.ConcatConfig.Length)
+
+	xyz := plan.Notes[1]
+	assert.Empty(t, xyz.Alias)
+	assert.True(t, xyz.Silent)
+	assert.Equal(t, 1500, xyz.StartSample)
+	assert.Nil(t, xyz.ResampleConfig)
+
+	b, err := json.Marshal(plan)
+	assert.NoError(t, err)
+
+	// the configurations use the same camelCase keys as the rest of the plan
+	var m struct {
+		Notes []map[string]json.RawMessage `json:"notes"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.JSONEq(t, `{
+		"pitch": 60, "velocity": 0, "offset": 0, "length": 650, "consonant": 0, "cutoff": 0,
+		"intensity": 1, "modulation": 0, "tempo": 120, "resolution": 480, "sampleRate": 1000, "channels": 1
+	}`, string(m.Notes[0]["resampleConfig"]))
+	assert.JSONEq(t, `{
+		"offset": 0, "length": 600, "overlap": 20, "intensity": 1, "sampleRate": 1000, "channels": 1,
+		"envelope": {"p1": 0, "p2": 5, "p3": 35, "p4": 0, "p5": 0, "v1": 0, "v2": 100, "v3": 100, "v4": 0, "v5": 100}
+	}`, string(m.Notes[0]["concatConfig"]))
+}
+
+func TestSynth_Plan_PitchBend(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a", Preutterance: 100, Overlap: 20},
+		},
+	}
+	synth := gotau.New(1000, vb, nopResampler{}, nil)
+
+	note := sequence.Note{
+		Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1,
+		PitchBend: sequence.Curve{
+			{X: -48, Y: 5900, Interp: sequence.CurveInterpolationLinear}, // 50 ms before the note
+			{X: 48, Y: 6000},
+		},
+	}
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes:    []sequence.Note{note},
+	}
+
+	// the sample starts 100 ms before the note, and the curve is extended to cover all of it
+	cfg := synth.Plan(seq).Notes[0].ResampleConfig
+	assert.Equal(t, []gotau.CurvePointPlan{
+		{X: 0, Y: 5900},
+		{X: 48, Y: 5900, Interp: sequence.CurveInterpolationLinear},
+		{X: 144, Y: 6000},
+		{X: 625, Y: 6000},
+	}, cfg.PitchBend)
+
+	// vibrato is added to the pitch bend in the same units
+	seq.Notes[0].Vibrato = &sequence.Vibrato{Length: 100, Period: 100, Depth: 20}
+	for _, pt := range synth.Plan(seq).Notes[0].ResampleConfig.PitchBend {
+		assert.InDelta(t, 5950, pt.Y, 70)
+	}
+}
@@ -0,0 +1,237 @@
+package gotau
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/SladkyCitron/gotau/cache"
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/effect"
+	"github.com/SladkyCitron/gotau/loudness"
+	"github.com/SladkyCitron/gotau/phonemizer"
+	"github.com/SladkyCitron/gotau/pitch"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/SladkyCitron/resona/codec/wav"
+)
+
+// DefaultSampleRate is the sample rate used by [RenderToWriter] and [RenderToBuffer] by default.
+const DefaultSampleRate = 44100
+
+// RenderOptions represents the options for passing into [RenderToWriter] and [RenderToBuffer].
+// Only Resampler is required, the rest falls back to the defaults of the [Synth].
+type RenderOptions struct {
+	// Resampler is the resampler. Required.
+	Resampler resample.Resampler
+
+	// Concatenator is the concatenator. If it's nil, the built-in [concat.Wavtool] is used.
+	Concatenator concat.Concatenator
+
+	// Phonemizer is the phonemizer. If it's nil, [phonemizer.Default] is used.
+	Phonemizer phonemizer.Phonemizer
+
+	// AutoPitch generates the pitch curves of notes without pitch bend data.
+	// If it's nil, such notes are rendered with a flat pitch.
+	AutoPitch *pitch.AutoPitch
+
+	// AutoVibrato generates the vibrato of notes without vibrato data.
+	// If it's nil, such notes have no vibrato.
+	AutoVibrato *pitch.AutoVibrato
+
+	// ResamplerCache is the cache for storing resampled notes. If it's nil, nothing is cached.
+	ResamplerCache cache.Cache
+
+	// SampleCache is the cache for decoded voicebank samples. If it's nil, [DefaultSampleCache] is used.
+	SampleCache *SampleCache
+
+	// SampleRate is the sample rate of the output in Hz. If it's 0, [DefaultSampleRate] is used.
+	SampleRate int
+
+	// SampleFormat is the sample format of the output file written by [RenderToWriter].
+	// If it's the zero value, 16-bit little-endian integer samples are written.
+	SampleFormat afmt.SampleFormat
+
+	// Breath configures the automatic insertion of breaths. If it's nil, no breaths are inserted. See [Synth.SetBreath].
+	Breath *Breath
+
+	// Effects is the effect (usually an [effect.Chain]) applied to the output before the loudness normalization.
+	// If it's nil, no effects are applied.
+	Effects effect.Effect
+
+	// Loudness is the target integrated loudness of the output in LUFS (e.g. -14).
+	// If it's nil, the loudness isn't normalized. See [loudness.Normalize].
+	Loudness *float64
+
+	// TruePeakLimit is the ceiling of the true-peak limiter in dBTP (e.g. -1), applied after the loudness normalization.
+	// If it's nil, no limiter is used. See [loudness.Limiter].
+	TruePeakLimit *float64
+
+	// Concurrency is the maximum number of notes resampled in parallel. If it's 0, notes are resampled
+	// one at a time. Higher values require the resampler and the resampler cache to be safe for
+	// concurrent use. See [Synth.SetConcurrency].
+	Concurrency int
+
+	// Context is the context used for rendering. If it's nil, [context.Background] is used.
+	Context context.Context
+
+	// Logger is the logger. If it's nil, nothing is logged.
+	Logger *slog.Logger
+
+	// ProgressFunc is the function that receives rendering progress updates. Optional.
+	ProgressFunc ProgressFunc
+}
+
+// RenderStats represents the statistics of a finished render.
+type RenderStats struct {
+	// Samples is the number of rendered samples.
+	Samples int64
+
+	// Duration is the duration of the rendered audio.
+	Duration time.Duration
+
+	// Progress is the final rendering progress (notes rendered, cache hits and misses, elapsed time).
+	Progress Progress
+}
+
+// RenderToWriter renders the sequence with the voicebank and writes it to w as a mono WAV file.
+func RenderToWriter(w io.WriteSeeker, seq sequence.Sequence, vb *voicebank.Voicebank, opts RenderOptions) (RenderStats, error) {
+	synth, err := newRenderSynth(seq, vb, opts)
+	if err != nil {
+		return RenderStats{}, err
+	}
+
+	sampleFmt := opts.SampleFormat
+	if sampleFmt == (afmt.SampleFormat{}) {
+		sampleFmt = afmt.SampleFormat{BitDepth: 16, Encoding: afmt.SampleEncodingInt, Endian: binary.LittleEndian}
+	}
+
+	var wavFormat uint16
+	switch sampleFmt.Encoding {
+	case afmt.SampleEncodingInt, afmt.SampleEncodingUint:
+		wavFormat = wav.FormatInt
+	case afmt.SampleEncodingFloat:
+		wavFormat = wav.FormatFloat
+	default:
+		return RenderStats{}, fmt.Errorf("gotau: invalid sample format: %s", sampleFmt.String())
+	}
+
+	enc, err := wav.NewEncoder(w, synth.Format(), sampleFmt, wavFormat)
+	if err != nil {
+		return RenderStats{}, fmt.Errorf("gotau: failed to create wav encoder: %w", err)
+	}
+
+	out, err := renderOutput(synth, opts)
+	if err != nil {
+		return RenderStats{}, err
+	}
+
+	n, err := aio.Copy(enc, out)
+	stats := synth.renderStats(n)
+	if err != nil {
+		return stats, fmt.Errorf("gotau: failed to render: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return stats, fmt.Errorf("gotau: failed to close wav encoder: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RenderToBuffer renders the sequence with the voicebank and returns the mono samples.
+func RenderToBuffer(seq sequence.Sequence, vb *voicebank.Voicebank, opts RenderOptions) ([]float32, RenderStats, error) {
+	synth, err := newRenderSynth(seq, vb, opts)
+	if err != nil {
+		return nil, RenderStats{}, err
+	}
+
+	r, err := renderOutput(synth, opts)
+	if err != nil {
+		return nil, RenderStats{}, err
+	}
+
+	out := make([]float32, 0, startBufSize)
+	buf := make([]float32, startBufSize)
+	for {
+		n, err := r.ReadSamples(buf)
+		out = append(out, buf[:n]...)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return out, synth.renderStats(int64(len(out))), fmt.Errorf("gotau: failed to render: %w", err)
+		}
+	}
+
+	return out, synth.renderStats(int64(len(out))), nil
+}
+
+func newRenderSynth(seq sequence.Sequence, vb *voicebank.Voicebank, opts RenderOptions) (*Synth, error) {
+	if opts.Resampler == nil {
+		return nil, errors.New("gotau: no resampler")
+	}
+
+	sr := opts.SampleRate
+	if sr == 0 {
+		sr = DefaultSampleRate
+	}
+
+	synth := New(sr, vb, opts.Resampler, opts.Concatenator)
+	if opts.Phonemizer != nil {
+		synth.SetPhonemizer(opts.Phonemizer)
+	}
+	synth.SetAutoPitch(opts.AutoPitch)
+	synth.SetAutoVibrato(opts.AutoVibrato)
+	synth.SetBreath(opts.Breath)
+	if opts.ResamplerCache != nil {
+		synth.SetResamplerCache(opts.ResamplerCache)
+	}
+	if opts.SampleCache != nil {
+		synth.SetSampleCache(opts.SampleCache)
+	}
+	if opts.Context != nil {
+		synth.SetContext(opts.Context)
+	}
+	synth.SetLogger(opts.Logger)
+	synth.SetProgressFunc(opts.ProgressFunc)
+
+	synth.SetConcurrency(opts.Concurrency)
+
+	synth.EnqueueSequence(seq)
+	return synth, nil
+}
+
+// renderOutput returns the output stage (effects, loudness normalization and limiter) of the Synth.
+func renderOutput(synth *Synth, opts RenderOptions) (aio.SampleReader, error) {
+	var out aio.SampleReader = synth
+	if opts.Effects != nil {
+		out = opts.Effects.Apply(out, synth.Format())
+	}
+	if opts.Loudness != nil {
+		r, err := loudness.Normalize(out, synth.Format(), *opts.Loudness)
+		if err != nil {
+			return nil, fmt.Errorf("gotau: failed to render: %w", err)
+		}
+		out = r
+	}
+	if opts.TruePeakLimit != nil {
+		out = loudness.NewLimiter(out, synth.Format(), *opts.TruePeakLimit)
+	}
+	return out, nil
+}
+
+func (s *Synth) renderStats(samples int64) RenderStats {
+	return RenderStats{
+		Samples:  samples,
+		Duration: time.Duration(float64(samples) / float64(s.sr) * float64(time.Second)),
+		Progress: s.Progress(),
+	}
+}
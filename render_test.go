@@ -0,0 +1,122 @@
+package gotau_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopResampler struct{}
+
+func (nopResampler) ID() string { return "nop" }
+
+func (nopResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	return in, nil
+}
+
+func TestRenderToBuffer(t *testing.T) {
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 0, Duration: 480, Lyric: "xyz", Note: 60}, // not in the voicebank, rendered as silence
+		},
+	}
+
+	samples, stats, err := gotau.RenderToBuffer(seq, &voicebank.Voicebank{}, gotau.RenderOptions{
+		Resampler:  nopResampler{},
+		SampleRate: 1000,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 500)
+	assert.Equal(t, int64(500), stats.Samples)
+	assert.Equal(t, 500*time.Millisecond, stats.Duration)
+	assert.Equal(t, 1, stats.Progress.NotesRendered)
+}
+
+func TestRenderToBuffer_NoResampler(t *testing.T) {
+	_, _, err := gotau.RenderToBuffer(sequence.Sequence{}, &voicebank.Voicebank{}, gotau.RenderOptions{})
+	assert.Error(t, err)
+}
+
+func TestRenderToBuffer_Gaps(t *testing.T) {
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 960, Duration: 480, Lyric: "xyz", Note: 60}, // out of order, after a rest
+			{Position: 0, Duration: 480, Lyric: "xyz", Note: 60},
+		},
+	}
+
+	samples, _, err := gotau.RenderToBuffer(seq, &voicebank.Voicebank{}, gotau.RenderOptions{
+		Resampler:  nopResampler{},
+		SampleRate: 1000,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1500)
+}
+
+func TestRenderToBuffer_LoudnessLimiter(t *testing.T) {
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 0, Duration: 480, Lyric: "xyz", Note: 60},
+		},
+	}
+
+	target, ceiling := -14.0, -1.0
+	samples, stats, err := gotau.RenderToBuffer(seq, &voicebank.Voicebank{}, gotau.RenderOptions{
+		Resampler:     nopResampler{},
+		SampleRate:    1000,
+		Loudness:      &target,
+		TruePeakLimit: &ceiling,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, samples, 500) // silence stays silent, and the limiter keeps the length
+	assert.Equal(t, int64(500), stats.Samples)
+}
+
+// inFlightResampler records the maximum number of concurrent Resample calls.
+type inFlightResampler struct {
+	delayResampler
+
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (r *inFlightResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	r.mu.Lock()
+	r.inFlight++
+	r.max = max(r.max, r.inFlight)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+	return r.delayResampler.Resample(ctx, in, cfg)
+}
+
+func TestRenderToBuffer_Concurrency(t *testing.T) {
+	vb := openTestVoicebank(t)
+	seq := concurrencyTestSeq()
+
+	serial := &inFlightResampler{}
+	want, _, err := gotau.RenderToBuffer(seq, vb, gotau.RenderOptions{Resampler: serial})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, serial.max) // one note at a time by default
+
+	parallel := &inFlightResampler{}
+	got, _, err := gotau.RenderToBuffer(seq, vb, gotau.RenderOptions{Resampler: parallel, Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, parallel.max) // the option is passed to the Synth as its limit
+	assert.Equal(t, want, got)
+}
@@ -3,6 +3,7 @@ package external
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -46,7 +47,7 @@ func (r *Resampler) ID() string {
 	return fmt.Sprintf("external:%s:%s", r.cmdName, r.analysisExt)
 }
 
-func (r *Resampler) Resample(in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+func (r *Resampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
 	input, err := r.createTempWav(in, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("external: failed to create temporary wav file: %w", err)
@@ -59,7 +60,8 @@ func (r *Resampler) Resample(in aio.SampleReader, cfg resample.ResampleConfig) (
 		flags = cfg.Flags
 	}
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		r.cmdName,
 		input,
 		output,
@@ -73,12 +75,15 @@ func (r *Resampler) Resample(in aio.SampleReader, cfg resample.ResampleConfig) (
 		strconv.FormatInt(int64(cfg.Intensity*100), 10),
 		strconv.FormatInt(int64(cfg.Modulation*100), 10),
 		"!"+strconv.FormatFloat(cfg.Tempo, 'f', -1, 64), // apparently the tempo starts with "!" and not "T"???
-		pitch.EncodeResamplerPitchBendString(cfg.PitchBend, cfg.Pitch, cfg.Length, cfg.Tempo, cfg.Resolution),
+		pitch.EncodeResamplerPitchBendString(cfg.PitchBend, cfg.Pitch, cfg.Length/1000, cfg.Tempo, cfg.Resolution),
 	)
 	if r.ConfigureCmd != nil {
 		r.ConfigureCmd(cmd)
 	}
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("external: failed to run resampler command: %w", err)
 	}
 
@@ -94,9 +99,9 @@ func (r *Resampler) Resample(in aio.SampleReader, cfg resample.ResampleConfig) (
 	return out, nil
 }
 
-func (r *Resampler) ResampleWithAnalysis(in aio.SampleReader, analysis io.Reader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+func (r *Resampler) ResampleWithAnalysis(ctx context.Context, in aio.SampleReader, analysis io.Reader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
 	if analysis == nil {
-		return r.Resample(in, cfg)
+		return r.Resample(ctx, in, cfg)
 	}
 
 	input, err := r.createTempWav(in, cfg)
@@ -116,7 +121,8 @@ func (r *Resampler) ResampleWithAnalysis(in aio.SampleReader, analysis io.Reader
 		flags = cfg.Flags
 	}
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		r.cmdName,
 		input,
 		output,
@@ -130,12 +136,15 @@ func (r *Resampler) ResampleWithAnalysis(in aio.SampleReader, analysis io.Reader
 		strconv.FormatInt(int64(cfg.Intensity*100), 10),
 		strconv.FormatInt(int64(cfg.Modulation*100), 10),
 		"!"+strconv.FormatFloat(cfg.Tempo, 'f', -1, 64),
-		pitch.EncodeResamplerPitchBendString(cfg.PitchBend, cfg.Pitch, cfg.Length, cfg.Tempo, cfg.Resolution),
+		pitch.EncodeResamplerPitchBendString(cfg.PitchBend, cfg.Pitch, cfg.Length/1000, cfg.Tempo, cfg.Resolution),
 	)
 	if r.ConfigureCmd != nil {
 		r.ConfigureCmd(cmd)
 	}
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("external: failed to run resampler command: %w", err)
 	}
 
@@ -155,7 +164,7 @@ func (r *Resampler) ResampleWithAnalysis(in aio.SampleReader, analysis io.Reader
 	return out, nil
 }
 
-func (r *Resampler) Analyze(in aio.SampleReader, format afmt.Format) (io.ReadCloser, error) {
+func (r *Resampler) Analyze(ctx context.Context, in aio.SampleReader, format afmt.Format) (io.ReadCloser, error) {
 	input, err := os.CreateTemp("", "gotau-externalresampler-analysis-*.wav")
 	if err != nil {
 		return nil, err
@@ -194,11 +203,14 @@ func (r *Resampler) Analyze(in aio.SampleReader, format afmt.Format) (io.ReadClo
 
 	dummyOutput := input.Name()[:len(input.Name())-len(filepath.Ext(input.Name()))] + "-out.wav"
 
-	cmd := exec.Command(r.cmdName, input.Name(), dummyOutput, "0", "0", "GN")
+	cmd := exec.CommandContext(ctx, r.cmdName, input.Name(), dummyOutput, "0", "0", "GN")
 	if r.ConfigureCmd != nil {
 		r.ConfigureCmd(cmd)
 	}
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("external: failed to run resampler command: %w", err)
 	}
 
@@ -239,13 +251,14 @@ func (r *Resampler) createTempWav(in aio.SampleReader, cfg resample.ResampleConf
 		_ = binary.Write(h, binary.LittleEndian, pt.Y)
 		_, _ = h.Write([]byte{byte(pt.Interp)})
 	}
-	path := filepath.Join(os.TempDir(), fmt.Sprintf("gotau-externalresampler-%016x.wav", h.Sum64()))
 
-	f, err := os.Create(path)
+	// the random suffix keeps concurrent renders of identical notes from clobbering each other
+	f, err := os.CreateTemp("", fmt.Sprintf("gotau-externalresampler-%016x-*.wav", h.Sum64()))
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
+	path := f.Name()
 
 	var wavFormat uint16
 	switch r.sampleFmt.Encoding {
@@ -1,6 +1,7 @@
 package resample
 
 import (
+	"context"
 	"io"
 
 	"github.com/SladkyCitron/gotau/sequence"
@@ -16,7 +17,8 @@ type Resampler interface {
 
 	// Resample renders a note from the given input sample using the provided
 	// resampling configuration (pitch, velocity, oto settings, pitch bend, etc.).
-	Resample(in aio.SampleReader, cfg ResampleConfig) (aio.SampleReader, error)
+	// It should stop and return ctx.Err() when ctx is canceled.
+	Resample(ctx context.Context, in aio.SampleReader, cfg ResampleConfig) (aio.SampleReader, error)
 }
 
 // Analyzer is the interface for resamplers that are capable of analysis
@@ -27,12 +29,12 @@ type Analyzer interface {
 	// ResampleWithAnalysis renders a note from the given input sample using the provided
 	// resampling configuration (pitch, velocity, oto settings, pitch bend, etc.) and
 	// analysis sidecar file. If analysis is nil, the resampler can generate a new one.
-	ResampleWithAnalysis(in aio.SampleReader, analysis io.Reader, cfg ResampleConfig) (aio.SampleReader, error)
+	ResampleWithAnalysis(ctx context.Context, in aio.SampleReader, analysis io.Reader, cfg ResampleConfig) (aio.SampleReader, error)
 
 	// Analyze analyzes the given input sample and generates an analysis sidecar file.
 	// The file format and extension is determined by [Analyzer.AnalysisExt].
 	// Closing is the caller's responsibility.
-	Analyze(in aio.SampleReader, format afmt.Format) (io.ReadCloser, error)
+	Analyze(ctx context.Context, in aio.SampleReader, format afmt.Format) (io.ReadCloser, error)
 
 	// AnalysisExt returns the file extension of the analysis sidecar file format
 	// used by this resampler (e.g. ".frq").
@@ -75,7 +77,8 @@ type ResampleConfig struct {
 	// It is only used for sampling the pitch bend curve.
 	Resolution int
 
-	// PitchBend is the pitch bend curve. It is a curve that maps time (in MIDI ticks) to pitch (in semitones).
+	// PitchBend is the pitch bend curve. It maps time in ticks from the start of the resampled sample
+	// (at Tempo and Resolution) to the absolute pitch in cents (MIDI note number * 100).
 	PitchBend sequence.Curve
 
 	// AudioFormat is the audio format of the input and output audio data.
@@ -0,0 +1,171 @@
+package gotau
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/SladkyCitron/gotau/convert"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/afmt"
+	"github.com/SladkyCitron/resona/codec"
+	"github.com/SladkyCitron/resona/freq"
+)
+
+// DefaultSampleCacheSize is the size of [DefaultSampleCache].
+const DefaultSampleCacheSize = 128 << 20 // 128 MiB
+
+// DefaultSampleCache is the sample cache that is shared by all Synths by default,
+// so e.g. consecutive renders and the tracks of a project decode each sample only once.
+var DefaultSampleCache = NewSampleCache(DefaultSampleCacheSize)
+
+// SampleCache is a memory-bounded LRU cache of decoded voicebank samples.
+//
+// Samples are stored already converted to the format of the [Synth], together with
+// the hash of the sample file, so notes that use the same alias neither read nor decode
+// the sample file again. A SampleCache is safe for concurrent use and can be shared
+// between multiple Synths (e.g. between the tracks of a project).
+//
+// Cached samples keep their voicebank in memory until they're evicted or the cache is cleared.
+type SampleCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	entries map[sampleKey]*list.Element
+	lru     *list.List // front is the most recently used
+}
+
+type sampleKey struct {
+	vb          *voicebank.Voicebank
+	path        string
+	sampleRate  freq.Frequency
+	numChannels int
+}
+
+// decodedSample is a decoded voicebank sample. It must not be modified, as it's shared between notes.
+type decodedSample struct {
+	key       sampleKey
+	samples   []float32
+	hash      [sha256.Size]byte // hash of the sample file
+	converted bool              // whether the sample had to be converted to the synth's format
+}
+
+func (d *decodedSample) size() int64 {
+	return int64(len(d.samples)) * 4
+}
+
+// NewSampleCache creates a new [SampleCache] that holds at most maxSize bytes of samples.
+// Samples larger than maxSize are not cached.
+func NewSampleCache(maxSize int64) *SampleCache {
+	return &SampleCache{
+		maxSize: maxSize,
+		entries: make(map[sampleKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Len returns the number of cached samples.
+func (c *SampleCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Size returns the total size of the cached samples in bytes.
+func (c *SampleCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Clear removes all samples from the cache.
+func (c *SampleCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.lru.Init()
+	c.size = 0
+}
+
+func (c *SampleCache) get(key sampleKey) (*decodedSample, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*decodedSample), true
+}
+
+func (c *SampleCache) put(sample *decodedSample) {
+	if sample.size() > c.maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[sample.key]; ok {
+		// loaded concurrently by another note
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[sample.key] = c.lru.PushFront(sample)
+	c.size += sample.size()
+
+	for c.size > c.maxSize {
+		oldest := c.lru.Back()
+		evicted := c.lru.Remove(oldest).(*decodedSample)
+		delete(c.entries, evicted.key)
+		c.size -= evicted.size()
+	}
+}
+
+// loadSample returns the decoded sample at the path in the voicebank, converted to the format.
+// It is loaded from the sample cache if possible.
+func (s *Synth) loadSample(path string, format afmt.Format) (*decodedSample, error) {
+	key := sampleKey{vb: s.vb, path: path, sampleRate: format.SampleRate, numChannels: format.NumChannels}
+	if s.sampleCache != nil {
+		if sample, ok := s.sampleCache.get(key); ok {
+			return sample, nil
+		}
+	}
+
+	b, err := fs.ReadFile(s.vb.FS(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	deco, _, err := codec.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// normalize the sample to the synth's format
+	converted := convert.NeedsConversion(deco.Format(), format)
+	r, err := convert.Reader(deco, deco.Format(), format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert voicebank sample: %w", err)
+	}
+
+	samples, err := dsp.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &decodedSample{
+		key:       key,
+		samples:   samples,
+		hash:      sha256.Sum256(b),
+		converted: converted,
+	}
+	if s.sampleCache != nil {
+		s.sampleCache.put(sample)
+	}
+	return sample, nil
+}
@@ -0,0 +1,156 @@
+package gotau_test
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleSize is the size of a decoded sample of the test voicebank in bytes.
+const sampleSize = 44100 * 400 / 1000 * 4
+
+// countingFS counts how often each file is opened.
+type countingFS struct {
+	fs.FS
+
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (f *countingFS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	f.opens[name]++
+	f.mu.Unlock()
+	return f.FS.Open(name)
+}
+
+func (f *countingFS) count(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opens[name]
+}
+
+// openCountingVoicebank opens the test voicebank and returns the filesystem that counts the opened samples.
+func openCountingVoicebank(t *testing.T) (*voicebank.Voicebank, *countingFS) {
+	t.Helper()
+	fsys := &countingFS{FS: os.DirFS("testdata/voicebank"), opens: make(map[string]int)}
+	vb, err := voicebank.Open(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clear(fsys.opens)
+	return vb, fsys
+}
+
+func renderWithCache(t *testing.T, vb *voicebank.Voicebank, c *gotau.SampleCache) []float32 {
+	synth := gotau.New(44100, vb, loopResampler{}, nil)
+	synth.SetSampleCache(c)
+	synth.EnqueueSequence(testSeq)
+	return renderAll(t, synth)
+}
+
+func TestSampleCache(t *testing.T) {
+	vb, fsys := openCountingVoicebank(t)
+	c := gotau.NewSampleCache(gotau.DefaultSampleCacheSize)
+
+	want := renderWithCache(t, vb, c)
+	assert.Equal(t, 1, fsys.count("a.wav")) // missed by the first note and hit by the last one
+	assert.Equal(t, 1, fsys.count("i.wav"))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(2*sampleSize), c.Size())
+
+	// another Synth hits the cache for all notes
+	got := renderWithCache(t, vb, c)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 1, fsys.count("a.wav"))
+	assert.Equal(t, 1, fsys.count("i.wav"))
+
+	c.Clear()
+	assert.Zero(t, c.Len())
+	assert.Zero(t, c.Size())
+	renderWithCache(t, vb, c)
+	assert.Equal(t, 2, fsys.count("a.wav"))
+}
+
+func TestSampleCache_Nil(t *testing.T) {
+	vb, fsys := openCountingVoicebank(t)
+
+	renderWithCache(t, vb, nil)
+	assert.Equal(t, 2, fsys.count("a.wav"))
+	assert.Equal(t, 1, fsys.count("i.wav"))
+}
+
+func TestSampleCache_Eviction(t *testing.T) {
+	vb, fsys := openCountingVoicebank(t)
+	c := gotau.NewSampleCache(sampleSize * 3 / 2) // room for one sample
+
+	renderWithCache(t, vb, c)
+	assert.Equal(t, 2, fsys.count("a.wav")) // evicted by i.wav before the last note
+	assert.Equal(t, 1, fsys.count("i.wav"))
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, int64(sampleSize), c.Size())
+
+	// samples larger than the cache aren't cached
+	small := gotau.NewSampleCache(sampleSize - 1)
+	renderWithCache(t, vb, small)
+	assert.Zero(t, small.Len())
+	assert.Zero(t, small.Size())
+}
+
+func TestSampleCache_Concurrent(t *testing.T) {
+	vb, _ := openCountingVoicebank(t)
+	c := gotau.NewSampleCache(gotau.DefaultSampleCacheSize)
+	want := renderWithCache(t, vb, nil)
+
+	var wg sync.WaitGroup
+	results := make([][]float32, 8)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			synth := gotau.New(44100, vb, loopResampler{}, nil)
+			synth.SetSampleCache(c)
+			synth.SetConcurrency(3)
+			synth.EnqueueSequence(testSeq)
+			p := make([]float32, 1000)
+			for {
+				n, err := synth.ReadSamples(p)
+				results[i] = append(results[i], p[:n]...)
+				if err != nil {
+					if err != io.EOF {
+						errs[i] = err
+					}
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, got := range results {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, want, got)
+	}
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(2*sampleSize), c.Size())
+}
+
+func TestDefaultSampleCache(t *testing.T) {
+	vb, fsys := openCountingVoicebank(t)
+
+	// Synths share the default cache unless they're given another one
+	for range 2 {
+		synth := gotau.New(44100, vb, loopResampler{}, nil)
+		synth.EnqueueSequence(testSeq)
+		renderAll(t, synth)
+	}
+	assert.Equal(t, 1, fsys.count("a.wav"))
+	assert.Equal(t, 1, fsys.count("i.wav"))
+}
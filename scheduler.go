@@ -2,18 +2,16 @@ package gotau
 
 import (
 	"cmp"
-	"iter"
 	"slices"
 
-	"github.com/SladkyCitron/gotau/internal/timeutil"
 	"github.com/SladkyCitron/gotau/sequence"
 )
 
 type scheduler struct {
-	queue   []sequence.Note
-	tpqn    int
-	bpm     float64
-	tickPos int
+	queue  []sequence.Note
+	next   int // index of the next note in queue; the notes before it have already been popped
+	tpqn   int
+	tempos sequence.TempoMap
 }
 
 func (s *scheduler) enqueue(notes ...sequence.Note) {
@@ -24,38 +22,46 @@ func (s *scheduler) enqueue(notes ...sequence.Note) {
 var sortFn = func(a, b sequence.Note) int { return cmp.Compare(a.Position, b.Position) }
 
 func (s *scheduler) ensureQueueSorted() {
-	if slices.IsSortedFunc(s.queue, sortFn) {
+	upcoming := s.queue[s.next:]
+	if slices.IsSortedFunc(upcoming, sortFn) {
 		return
 	}
-	slices.SortFunc(s.queue, sortFn)
-}
-
-// popSeq returns and dequeues all notes that are ready to be rendered up to seconds.
-func (s *scheduler) popSeq(seconds float64) iter.Seq[sequence.Note] {
-	return func(yield func(sequence.Note) bool) {
-		ticks := s.secondsToTicks(seconds)
-		for len(s.queue) > 0 && ticks > 0 {
-			note := s.queue[0]
-			ticks -= note.Duration
-			if !yield(note) {
-				return
-			}
-			s.queue = s.queue[1:]
-		}
+	slices.SortFunc(upcoming, sortFn)
+}
+
+// pop returns and dequeues the next note to be rendered.
+func (s *scheduler) pop() (sequence.Note, bool) {
+	if s.next >= len(s.queue) {
+		return sequence.Note{}, false
 	}
+	note := s.queue[s.next]
+	s.next++
+	return note, true
 }
 
 func (s *scheduler) peek() (sequence.Note, bool) {
-	if len(s.queue) == 0 {
+	if s.next >= len(s.queue) {
 		return sequence.Note{}, false
 	}
-	return s.queue[0], true
+	return s.queue[s.next], true
+}
+
+// rewind puts all popped notes back into the queue.
+func (s *scheduler) rewind() {
+	s.next = 0
+	s.ensureQueueSorted()
+}
+
+func (s *scheduler) tempoAt(tick int) float64 {
+	return s.tempos.TempoAt(tick)
 }
 
+// secondsToTicks converts the time in seconds to a position in ticks.
 func (s *scheduler) secondsToTicks(seconds float64) int {
-	return timeutil.SecondsToTicks(seconds, s.tpqn, s.bpm)
+	return s.tempos.SecondsToTicks(seconds, s.tpqn)
 }
 
-func (s *scheduler) ticksToSeconds(ticks int) float64 {
-	return timeutil.TicksToSeconds(ticks, s.tpqn, s.bpm)
+// ticksToSeconds converts the position in ticks to time in seconds.
+func (s *scheduler) ticksToSeconds(tick int) float64 {
+	return s.tempos.TicksToSeconds(tick, s.tpqn)
 }
@@ -0,0 +1,85 @@
+package sequence
+
+// Envelope represents the volume envelope of a note in the UTAU (wavtool) form.
+//
+// All positions are in milliseconds and relative to the rendered sample of the note,
+// which starts preutterance before the note itself. P1 is measured from the start of the sample,
+// P2 from P1, P5 from P2, P4 from the end of the sample and P3 from P4 (backwards).
+// The volumes are in percent (100 = original volume) and the note is silent
+// before P1 and after P4.
+type Envelope struct {
+	// P1 is the fade-in start in milliseconds.
+	P1 float64
+
+	// P2 is the fade-in end in milliseconds.
+	P2 float64
+
+	// P3 is the fade-out start in milliseconds.
+	P3 float64
+
+	// P4 is the fade-out end in milliseconds.
+	P4 float64
+
+	// P5 is the middle point in milliseconds.
+	P5 float64
+
+	// V1 is the volume at P1.
+	V1 float64
+
+	// V2 is the volume at P2.
+	V2 float64
+
+	// V3 is the volume at P3.
+	V3 float64
+
+	// V4 is the volume at P4.
+	V4 float64
+
+	// V5 is the volume at P5.
+	V5 float64
+}
+
+// DefaultEnvelope is the default UTAU envelope (0,5,35,0,100,100,0).
+var DefaultEnvelope = Envelope{
+	P1: 0, P2: 5, P3: 35, P4: 0, P5: 0,
+	V1: 0, V2: 100, V3: 100, V4: 0, V5: 100,
+}
+
+// Gain returns the envelope gain (1 = original volume) at t milliseconds
+// into a rendered sample of the given length in milliseconds.
+func (e Envelope) Gain(t, length float64) float64 {
+	t1 := e.P1
+	t2 := t1 + e.P2
+	t5 := t2 + e.P5
+	t4 := length - e.P4
+	t3 := t4 - e.P3
+
+	pts := [5]struct{ t, v float64 }{
+		{t1, e.V1},
+		{t2, e.V2},
+		{t5, e.V5},
+		{t3, e.V3},
+		{t4, e.V4},
+	}
+
+	// short notes can make the points cross; keep them in order like wavtool does
+	for i := 1; i < len(pts); i++ {
+		pts[i].t = max(pts[i].t, pts[i-1].t)
+	}
+
+	if t < pts[0].t || t > pts[len(pts)-1].t {
+		return 0
+	}
+
+	for i := 0; i < len(pts)-1; i++ {
+		a, b := pts[i], pts[i+1]
+		if t > b.t {
+			continue
+		}
+		if b.t <= a.t {
+			return b.v / 100
+		}
+		return lerp(a.v, b.v, (t-a.t)/(b.t-a.t)) / 100
+	}
+	return pts[len(pts)-1].v / 100
+}
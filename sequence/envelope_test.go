@@ -0,0 +1,27 @@
+package sequence_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope_Gain(t *testing.T) {
+	env := sequence.Envelope{P1: 0, P2: 10, P3: 10, P4: 0, V1: 0, V2: 100, V3: 100, V4: 0, V5: 100}
+
+	assert.InDelta(t, 0, env.Gain(0, 100), 1e-9)
+	assert.InDelta(t, 0.5, env.Gain(5, 100), 1e-9)
+	assert.InDelta(t, 1, env.Gain(50, 100), 1e-9)
+	assert.InDelta(t, 0.5, env.Gain(95, 100), 1e-9)
+	assert.InDelta(t, 0, env.Gain(100, 100), 1e-9)
+	assert.InDelta(t, 0, env.Gain(101, 100), 1e-9)
+}
+
+func TestEnvelope_Gain_P5(t *testing.T) {
+	env := sequence.Envelope{P1: 0, P2: 10, P3: 0, P4: 0, P5: 10, V1: 100, V2: 100, V3: 50, V4: 50, V5: 50}
+
+	assert.InDelta(t, 1, env.Gain(10, 100), 1e-9)
+	assert.InDelta(t, 0.75, env.Gain(15, 100), 1e-9)
+	assert.InDelta(t, 0.5, env.Gain(60, 100), 1e-9)
+}
@@ -0,0 +1,89 @@
+package sequence
+
+import "time"
+
+// Project represents a multi-track project (e.g. a full vocal arrangement with a lead,
+// harmonies and chorus lines). All tracks share the metadata and the tempo map.
+type Project struct {
+	// Metadata is the metadata.
+	Metadata Metadata
+
+	// Tempos is the list of tempo changes. It should be sorted by position (ascending).
+	// [Metadata.Tempo] is in effect before the first tempo change.
+	Tempos TempoMap
+
+	// Tracks is the list of tracks.
+	Tracks []Track
+}
+
+// Track represents a single vocal part of a [Project].
+type Track struct {
+	// Name is the human-readable name of the track (e.g. lead, harmony).
+	Name string
+
+	// VoicebankPath is the path to the voicebank used by the track.
+	// If it's empty, the project's [Metadata.VoicebankPath] is used.
+	VoicebankPath string
+
+	// Phonemizer is the name of the phonemizer used by the track (e.g. cv, vcv).
+	// It's up to the application to map it to a phonemizer implementation.
+	Phonemizer string
+
+	// Volume is the volume of the track in decibels (dB), where 0 is the original volume.
+	Volume float64
+
+	// Pan is the stereo position of the track from -1 (left) to 1 (right), where 0 is the center.
+	Pan float64
+
+	// Mute specifies whether the track is muted.
+	Mute bool
+
+	// Solo specifies whether the track is soloed. If any track is soloed, only soloed tracks are heard.
+	Solo bool
+
+	// Notes is the list of notes. It should be sorted by position (ascending).
+	Notes []Note
+
+	// Expressions maps resampler flags to curves of their values over time. See [Sequence.Expressions].
+	Expressions map[string]Curve
+
+	// Effects is the chain of post-processing effects of the track, in order. They are applied to the
+	// output of the track before mixing (see NewProjectMixer in the gotau package).
+	Effects []EffectConfig
+}
+
+// EffectConfig represents a post-processing effect and its parameters.
+// It's up to the application to map it to an implementation (e.g. with the effect package).
+type EffectConfig struct {
+	// Type is the type of the effect (e.g. eq, compressor, reverb).
+	Type string
+
+	// Params holds the parameters of the effect by name. Missing parameters use the effect's defaults.
+	Params map[string]float64
+}
+
+// Sequence returns the track with index i as a standalone [Sequence] that can be rendered on its own.
+func (p Project) Sequence(i int) Sequence {
+	track := p.Tracks[i]
+
+	meta := p.Metadata
+	if track.VoicebankPath != "" {
+		meta.VoicebankPath = track.VoicebankPath
+	}
+
+	return Sequence{
+		Metadata:    meta,
+		Notes:       track.Notes,
+		Tempos:      p.Tempos,
+		Expressions: track.Expressions,
+	}
+}
+
+// Duration returns the length of the longest track as a [time.Duration].
+func (p Project) Duration() time.Duration {
+	var d time.Duration
+	for i := range p.Tracks {
+		d = max(d, p.Sequence(i).Duration())
+	}
+	return d
+}
@@ -0,0 +1,34 @@
+package sequence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_Sequence(t *testing.T) {
+	proj := sequence.Project{
+		Metadata: sequence.Metadata{
+			VoicebankPath: "path/to/lead",
+			Resolution:    480,
+			Tempo:         120,
+		},
+		Tempos: sequence.TempoMap{{Position: 960, Tempo: 60}},
+		Tracks: []sequence.Track{
+			{Name: "lead", Notes: []sequence.Note{{Position: 0, Duration: 960}}},
+			{Name: "harmony", VoicebankPath: "path/to/harmony", Notes: []sequence.Note{{Position: 0, Duration: 1920}}},
+		},
+	}
+
+	lead := proj.Sequence(0)
+	assert.Equal(t, "path/to/lead", lead.Metadata.VoicebankPath)
+	assert.Equal(t, proj.Tracks[0].Notes, lead.Notes)
+	assert.Equal(t, proj.Tempos, lead.Tempos)
+
+	harmony := proj.Sequence(1)
+	assert.Equal(t, "path/to/harmony", harmony.Metadata.VoicebankPath)
+
+	assert.Equal(t, 3*time.Second, proj.Duration())
+}
@@ -3,7 +3,6 @@ package sequence
 import (
 	"time"
 
-	"github.com/SladkyCitron/gotau/internal/timeutil"
 	"gitlab.com/gomidi/midi/v2"
 )
 
@@ -14,8 +13,25 @@ type Sequence struct {
 
 	// Notes is the list of notes. It should be sorted by position (ascending).
 	Notes []Note
+
+	// Tempos is the list of tempo changes. It should be sorted by position (ascending).
+	// [Metadata.Tempo] is in effect before the first tempo change.
+	Tempos TempoMap
+
+	// Expressions maps resampler flags (e.g. [ExpressionGender]) to curves of their values over time (in MIDI ticks).
+	// Each note gets the value at its start as a flag, unless the note sets the flag itself.
+	Expressions map[string]Curve
 }
 
+// Common expressions (resampler flags) used as keys of [Sequence.Expressions].
+// The supported flags and their ranges depend on the resampler.
+const (
+	ExpressionGender      = "g"
+	ExpressionBreathiness = "B"
+	ExpressionBrightness  = "bri"
+	ExpressionTension     = "Mt"
+)
+
 // Metadata represents the metadata of a sequence.
 type Metadata struct {
 	// Name is the human-readable name of the sequence (e.g. project name, song name).
@@ -30,7 +46,7 @@ type Metadata struct {
 	// Resolution is the number of MIDI ticks per quarter note (TPQN).
 	Resolution int
 
-	// Tempo is the tempo of the sequence in beats per minute (BPM).
+	// Tempo is the initial tempo of the sequence in beats per minute (BPM).
 	Tempo float64
 }
 
@@ -68,12 +84,17 @@ type Note struct {
 	// StartPoint is the time where to begin sampling inside the audio file (in milliseconds).
 	StartPoint *float64
 
-	// Envelope is the volume envelope curve. It should have at most 5 points.
-	Envelope Curve
+	// Envelope is the volume envelope. If it's omitted, [DefaultEnvelope] is used.
+	Envelope *Envelope
 
-	// PitchBend is the pitch bend curve. It is a curve that maps time (in MIDI ticks) to pitch (in semitones).
+	// PitchBend is the pitch bend curve. It maps time in MIDI ticks from the start of the note
+	// (negative before it, e.g. in the preutterance) to the absolute pitch in cents (MIDI note number * 100).
+	// Before the first point and after the last one, the pitch stays at the value of that point.
 	PitchBend Curve
 
+	// Vibrato is the vibrato, added on top of the pitch bend curve. If it's nil, the note has no vibrato.
+	Vibrato *Vibrato
+
 	// Flags is a string of flags for passing to the resampler. These can be resampler-specific.
 	Flags string
 }
@@ -84,16 +105,26 @@ type Sequencer interface {
 	Sequence() Sequence
 }
 
-// Len returns the sequence's length in MIDI ticks.
+// Len returns the sequence's length in MIDI ticks, i.e. the end tick of the note that ends last.
+// Gaps between notes (rests) are included.
 func (s Sequence) Len() int {
-	len := 0
+	end := 0
 	for _, note := range s.Notes {
-		len += note.Duration
+		end = max(end, note.Position+note.Duration)
+	}
+	return end
+}
+
+// TempoMap returns the complete tempo map of the sequence, starting with [Metadata.Tempo].
+func (s Sequence) TempoMap() TempoMap {
+	m := make(TempoMap, 0, len(s.Tempos)+1)
+	if len(s.Tempos) == 0 || s.Tempos[0].Position > 0 {
+		m = append(m, TempoEvent{Position: 0, Tempo: s.Metadata.Tempo})
 	}
-	return len
+	return append(m, s.Tempos...)
 }
 
 // Duration returns the sequence's length as a [time.Duration].
 func (s Sequence) Duration() time.Duration {
-	return timeutil.TicksToDuration(s.Len(), s.Metadata.Resolution, s.Metadata.Tempo)
+	return s.TempoMap().TicksToDuration(s.Len(), s.Metadata.Resolution)
 }
@@ -23,6 +23,17 @@ func TestSequence_Len(t *testing.T) {
 	assert.Equal(t, 1920, seq.Len())
 }
 
+func TestSequence_Len_GapsOverlaps(t *testing.T) {
+	seq := sequence.Sequence{
+		Notes: []sequence.Note{
+			{Position: 960, Duration: 960}, // out of order
+			{Position: 0, Duration: 480},
+			{Position: 1200, Duration: 240}, // inside the first note
+		},
+	}
+	assert.Equal(t, 1920, seq.Len())
+}
+
 func TestSequence_Duration(t *testing.T) {
 	seq := sequence.Sequence{
 		Metadata: sequence.Metadata{
@@ -37,3 +48,20 @@ func TestSequence_Duration(t *testing.T) {
 	}
 	assert.Equal(t, 2*time.Second, seq.Duration())
 }
+
+func TestSequence_Duration_TempoChange(t *testing.T) {
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{
+			Resolution: 480,
+			Tempo:      120,
+		},
+		Notes: []sequence.Note{
+			{Position: 0, Duration: 960},
+			{Position: 960, Duration: 960},
+		},
+		Tempos: sequence.TempoMap{
+			{Position: 960, Tempo: 60},
+		},
+	}
+	assert.Equal(t, 3*time.Second, seq.Duration())
+}
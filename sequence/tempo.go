@@ -0,0 +1,87 @@
+package sequence
+
+import (
+	"sort"
+	"time"
+
+	"github.com/SladkyCitron/gotau/internal/timeutil"
+)
+
+// TempoEvent represents a tempo change in the sequence.
+type TempoEvent struct {
+	// Position is the position of the tempo change in MIDI ticks.
+	Position int
+
+	// Tempo is the new tempo in beats per minute (BPM).
+	Tempo float64
+}
+
+// TempoMap maps positions in MIDI ticks to tempos. It is a list of tempo events sorted by position (ascending).
+// The tempo of the first event also applies before it.
+type TempoMap []TempoEvent
+
+// TempoAt returns the tempo in effect at the tick. It returns 0 if the map is empty.
+func (m TempoMap) TempoAt(tick int) float64 {
+	if len(m) == 0 {
+		return 0
+	}
+	i := sort.Search(len(m), func(i int) bool { return m[i].Position > tick })
+	return m[max(i-1, 0)].Tempo
+}
+
+// TicksToSeconds converts the position in MIDI ticks to seconds, honoring all tempo changes before it.
+func (m TempoMap) TicksToSeconds(tick int, tpqn int) float64 {
+	if len(m) == 0 {
+		return 0
+	}
+	if tick < 0 {
+		return timeutil.TicksToSeconds(tick, tpqn, m[0].Tempo)
+	}
+
+	seconds := 0.0
+	pos := 0
+	for i := 0; i < len(m) && pos < tick; i++ {
+		end := tick
+		if i+1 < len(m) {
+			end = min(end, m[i+1].Position)
+		}
+		if end > pos {
+			seconds += timeutil.TicksToSeconds(end-pos, tpqn, m[i].Tempo)
+			pos = end
+		}
+	}
+	return seconds
+}
+
+// SecondsToTicks converts the time in seconds to a position in MIDI ticks, honoring all tempo changes before it.
+func (m TempoMap) SecondsToTicks(seconds float64, tpqn int) int {
+	if len(m) == 0 {
+		return 0
+	}
+	if seconds < 0 {
+		return timeutil.SecondsToTicks(seconds, tpqn, m[0].Tempo)
+	}
+
+	pos := 0
+	for i := range m {
+		if i+1 < len(m) {
+			next := m[i+1].Position
+			if next <= pos {
+				continue // empty segment
+			}
+			segment := timeutil.TicksToSeconds(next-pos, tpqn, m[i].Tempo)
+			if seconds >= segment {
+				seconds -= segment
+				pos = next
+				continue
+			}
+		}
+		return pos + timeutil.SecondsToTicks(seconds, tpqn, m[i].Tempo)
+	}
+	return pos
+}
+
+// TicksToDuration converts the position in MIDI ticks to a [time.Duration], honoring all tempo changes before it.
+func (m TempoMap) TicksToDuration(tick int, tpqn int) time.Duration {
+	return time.Duration(m.TicksToSeconds(tick, tpqn) * 1e9)
+}
@@ -0,0 +1,35 @@
+package sequence_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTempoMap = sequence.TempoMap{
+	{Position: 0, Tempo: 120},
+	{Position: 960, Tempo: 60},
+}
+
+func TestTempoMap_TempoAt(t *testing.T) {
+	assert.Equal(t, 120.0, testTempoMap.TempoAt(-10))
+	assert.Equal(t, 120.0, testTempoMap.TempoAt(959))
+	assert.Equal(t, 60.0, testTempoMap.TempoAt(960))
+	assert.Equal(t, 60.0, testTempoMap.TempoAt(5000))
+	assert.Equal(t, 0.0, sequence.TempoMap{}.TempoAt(0))
+}
+
+func TestTempoMap_TicksToSeconds(t *testing.T) {
+	assert.InDelta(t, 0.5, testTempoMap.TicksToSeconds(480, 480), 1e-9)
+	assert.InDelta(t, 1, testTempoMap.TicksToSeconds(960, 480), 1e-9)
+	assert.InDelta(t, 2, testTempoMap.TicksToSeconds(1440, 480), 1e-9)
+	assert.InDelta(t, -0.5, testTempoMap.TicksToSeconds(-480, 480), 1e-9)
+}
+
+func TestTempoMap_SecondsToTicks(t *testing.T) {
+	assert.Equal(t, 480, testTempoMap.SecondsToTicks(0.5, 480))
+	assert.Equal(t, 960, testTempoMap.SecondsToTicks(1, 480))
+	assert.Equal(t, 1440, testTempoMap.SecondsToTicks(2, 480))
+	assert.Equal(t, -480, testTempoMap.SecondsToTicks(-0.5, 480))
+}
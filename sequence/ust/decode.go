@@ -16,8 +16,34 @@ import (
 
 var noteRe *regexp.Regexp = regexp.MustCompile(`#\d+`)
 
+type decodeConfig struct {
+	logger *slog.Logger
+}
+
+// Option represents an option for passing into [Decode].
+type Option func(*decodeConfig)
+
+// WithLogger specifies the logger for warnings about skipped content.
+// By default (or if logger is nil), nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *decodeConfig) {
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		cfg.logger = logger
+	}
+}
+
 // Decode decodes a UST file.
-func Decode(r io.Reader) (file *File, err error) {
+func Decode(r io.Reader, opts ...Option) (file *File, err error) {
+	cfg := &decodeConfig{
+		logger: slog.New(slog.DiscardHandler),
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	// detect encoding, step 1: sniff
 	const sniffLen = 256
 	buf := make([]byte, sniffLen)
@@ -80,7 +106,7 @@ func Decode(r io.Reader) (file *File, err error) {
 					return nil, fmt.Errorf("failed to parse note %s: %w", sec.Name(), err)
 				}
 			} else {
-				slog.Warn("invalid section, skipping", "name", sec.Name())
+				cfg.logger.Warn("invalid section, skipping", "name", sec.Name())
 				continue
 			}
 		}
@@ -234,9 +260,29 @@ func (f *File) parseNote(sec *ini.Section) (err error) {
 		note.PitchBend = _pb
 	}
 
+	// Vibrato
+	note.Vibrato = nil
+	if key, err := sec.GetKey("VBR"); err == nil && key.String() != "" {
+		vibrato, err := ParseVibrato(key.String())
+		if err != nil {
+			return fmt.Errorf("failed to parse vibrato: %w", err)
+		}
+		note.Vibrato = vibrato
+	}
+
 	// Flags
 	note.Flags = sec.Key("Flags").String()
 
+	// Tempo
+	note.Tempo = nil
+	if key, err := sec.GetKey("Tempo"); err == nil && key.String() != "" {
+		tempo, err := strconv.ParseFloat(key.String(), 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse tempo: %w", err)
+		}
+		note.Tempo = &tempo
+	}
+
 	f.Notes = append(f.Notes, note)
 
 	return nil
@@ -1,8 +1,11 @@
 package ust_test
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/SladkyCitron/gotau/sequence/ust"
@@ -63,6 +66,13 @@ func TestDecode(t *testing.T) {
 							Ys:     []float64{0, 42},
 							Modes:  []ust.PitchBendMode{ust.PitchBendModeLinear, ust.PitchBendModeSine},
 						},
+						Vibrato: &ust.Vibrato{
+							Length:  65,
+							Cycle:   180,
+							Depth:   35,
+							FadeIn:  20,
+							FadeOut: 20,
+						},
 						Flags: "g0",
 					},
 				},
@@ -190,3 +200,40 @@ func fileIsEqual(t *testing.T, expect, actual *ust.File) {
 func float64Ptr(v float64) *float64 {
 	return &v
 }
+
+const invalidSectionUST = `[#VERSION]
+UST Version1.2
+[#SETTING]
+Tempo=120
+Mode2=true
+[#PLUGIN]
+Foo=bar
+[#0000]
+Length=480
+Lyric=a
+NoteNum=60
+[#TRACKEND]
+`
+
+func TestDecode_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	file, err := ust.Decode(strings.NewReader(invalidSectionUST), ust.WithLogger(logger))
+	assert.NoError(t, err)
+	assert.Len(t, file.Notes, 1)
+	assert.Contains(t, buf.String(), `level=WARN msg="invalid section, skipping" name=#PLUGIN`)
+}
+
+func TestDecode_NoLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(defaultLogger)
+
+	_, err := ust.Decode(strings.NewReader(invalidSectionUST), ust.WithLogger(nil))
+	assert.NoError(t, err)
+	_, err = ust.Decode(strings.NewReader(invalidSectionUST))
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
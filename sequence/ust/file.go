@@ -1,7 +1,9 @@
 package ust
 
 import (
+	"github.com/SladkyCitron/gotau/internal/timeutil"
 	"github.com/SladkyCitron/gotau/sequence"
+	"gitlab.com/gomidi/midi/v2"
 	"gopkg.in/ini.v1"
 )
 
@@ -26,14 +28,19 @@ func (f *File) Sequence() sequence.Sequence {
 	}
 
 	var position int
+	tempo := f.Settings.Tempo
 	for _, note := range f.Notes {
+		// tempo changes can also be placed on rests
+		if note.Tempo != nil {
+			seq.Tempos = append(seq.Tempos, sequence.TempoEvent{Position: position, Tempo: *note.Tempo})
+			tempo = *note.Tempo
+		}
+
 		if IsLyricRest(note.Lyric) {
 			position += note.Length
 			continue
 		}
 
-		msPerTick := 60000 / (f.Settings.Tempo * float64(seq.Metadata.Resolution))
-
 		seq.Notes = append(seq.Notes, sequence.Note{
 			Position:     position,
 			Duration:     note.Length,
@@ -45,99 +52,103 @@ func (f *File) Sequence() sequence.Sequence {
 			Preutterance: note.Preutterance,
 			VoiceOverlap: note.VoiceOverlap,
 			StartPoint:   note.StartPoint,
-			Envelope:     envelopeToCurve(note.Envelope, msPerTick*float64(note.Length)),
-			PitchBend:    pitchBendToCurve(note.PitchBend),
+			Envelope:     envelopeToSequence(note.Envelope),
+			PitchBend:    pitchBendToCurve(note.PitchBend, note.NoteNum, tempo, seq.Metadata.Resolution),
+			Vibrato:      vibratoToSequence(note.Vibrato),
 		})
 		position += note.Length
 	}
 	return seq
 }
 
-func envelopeToCurve(env *Envelope, noteDurMs float64) sequence.Curve {
-	points := make(sequence.Curve, 0, 5)
-
+func envelopeToSequence(env *Envelope) *sequence.Envelope {
 	if env == nil {
-		return points
-	}
-
-	add := func(t, v float64) {
-		// t = ticks
-		// v = percentage
-		points = append(points, sequence.CurvePoint{
-			X:      int(t),
-			Y:      v / 100,
-			Interp: sequence.CurveInterpolationLinear,
-		})
+		return nil
 	}
 
-	resolveV := func(value EnvelopeValue) float64 {
+	// the positions and volumes are already in milliseconds and percent,
+	// auto values fall back to the UTAU defaults
+	resolve := func(value EnvelopeValue, def float64) float64 {
 		if value.Auto {
-			return 0
-		} else {
-			return value.Value
+			return def
 		}
+		return value.Value
 	}
 
-	add(env.P1.Value, resolveV(env.V1))
-	add(env.P2.Value, resolveV(env.V2))
-	add(env.P3.Value, resolveV(env.V3))
-
-	p4 := env.P4.Value
-	if env.P4.Auto {
-		p4 = noteDurMs
-	}
-	add(p4, resolveV(env.V4))
-
-	if !env.P5.Auto || !env.V5.Auto {
-		p5 := env.P5.Value
-		if env.P5.Auto {
-			p5 = noteDurMs
-		}
-		v5 := env.V5.Value
-		if env.V5.Auto {
-			v5 = 0
-		}
-		add(p5, v5)
+	v2 := resolve(env.V2, 0)
+	return &sequence.Envelope{
+		P1: resolve(env.P1, 0),
+		P2: resolve(env.P2, 0),
+		P3: resolve(env.P3, 0),
+		P4: resolve(env.P4, 0),
+		P5: resolve(env.P5, 0),
+		V1: resolve(env.V1, 0),
+		V2: v2,
+		V3: resolve(env.V3, 0),
+		V4: resolve(env.V4, 0),
+		V5: resolve(env.V5, v2), // no middle point
 	}
-
-	return points
 }
 
-func pitchBendToCurve(pb *PitchBend) sequence.Curve {
+// pitchBendToCurve converts the Mode2 pitch bend of a note to the form of [sequence.Note.PitchBend]:
+// the times in milliseconds from the start of the note become ticks at the tempo, and the pitches
+// relative to the note in tenths of a semitone become absolute pitches in cents.
+func pitchBendToCurve(pb *PitchBend, noteNum midi.Note, tempo float64, tpqn int) sequence.Curve {
 	if pb == nil {
-		return sequence.Curve{}
+		return nil
 	}
 	if (pb.Start.X == 0 && pb.Start.Y == 0) || len(pb.Widths) == 0 {
-		return sequence.Curve{}
+		return nil
 	}
 
-	// PBY defaults to 0 for every segment
-	for len(pb.Ys) < len(pb.Widths) {
-		pb.Ys = append(pb.Ys, 0)
+	toTicks := func(ms float64) int {
+		return timeutil.SecondsToTicks(ms/1000, tpqn, tempo)
 	}
-
-	// PBM defaults to sine
-	for len(pb.Modes) < len(pb.Widths) {
-		pb.Modes = append(pb.Modes, PitchBendModeSine)
+	toCents := func(y float64) float64 {
+		return float64(noteNum)*100 + y*10
 	}
 
-	points := sequence.Curve{}
-
+	points := make(sequence.Curve, 0, len(pb.Widths)+1)
 	x := pb.Start.X
 	y := pb.Start.Y
-	for i := range pb.Widths {
+	for i, width := range pb.Widths {
+		// PBM defaults to sine
+		mode := PitchBendModeSine
+		if i < len(pb.Modes) {
+			mode = pb.Modes[i]
+		}
 		points = append(points, sequence.CurvePoint{
-			X:      int(x),
-			Y:      y,
-			Interp: convertPBM(pb.Modes[i]),
+			X:      toTicks(x),
+			Y:      toCents(y),
+			Interp: convertPBM(mode),
 		})
-		x += pb.Widths[i]
-		y = pb.Ys[i]
+
+		x += width
+		y = 0 // PBY defaults to 0 for every segment
+		if i < len(pb.Ys) {
+			y = pb.Ys[i]
+		}
 	}
+	points = append(points, sequence.CurvePoint{X: toTicks(x), Y: toCents(y)})
 
 	return points
 }
 
+func vibratoToSequence(vbr *Vibrato) *sequence.Vibrato {
+	if vbr == nil || vbr.Length == 0 || vbr.Depth == 0 {
+		return nil
+	}
+	return &sequence.Vibrato{
+		Length:  vbr.Length,
+		Period:  vbr.Cycle,
+		Depth:   vbr.Depth,
+		FadeIn:  vbr.FadeIn,
+		FadeOut: vbr.FadeOut,
+		Phase:   vbr.Phase,
+		Height:  vbr.Height,
+	}
+}
+
 func convertPBM(mode PitchBendMode) sequence.CurveInterpolation {
 	return sequence.CurveInterpolation(mode) // this is enough for now
 }
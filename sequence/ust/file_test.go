@@ -0,0 +1,80 @@
+package ust_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/sequence/ust"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_Sequence_Envelope(t *testing.T) {
+	env, err := ust.ParseEnvelope("0,5,35,0,100,100,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &ust.File{
+		Settings: ust.Settings{Tempo: 120},
+		Notes: []ust.Note{
+			{Length: 480, Lyric: "a", NoteNum: 60, Intensity: 100, Envelope: env},
+			{Length: 480, Lyric: "R", NoteNum: 60, Intensity: 100},
+			{Length: 480, Lyric: "a", NoteNum: 60, Intensity: 100},
+		},
+	}
+
+	seq := f.Sequence()
+
+	// positions stay in milliseconds, independent of the tempo
+	assert.Equal(t, &sequence.DefaultEnvelope, seq.Notes[0].Envelope)
+	assert.Nil(t, seq.Notes[1].Envelope)
+}
+
+func TestFile_Sequence_Tempo(t *testing.T) {
+	tempo := 60.0
+	f := &ust.File{
+		Settings: ust.Settings{Tempo: 120},
+		Notes: []ust.Note{
+			{Length: 480, Lyric: "a", NoteNum: 60, Intensity: 100},
+			{Length: 480, Lyric: "R", NoteNum: 60, Intensity: 100, Tempo: &tempo},
+			{Length: 480, Lyric: "a", NoteNum: 60, Intensity: 100},
+		},
+	}
+
+	seq := f.Sequence()
+
+	assert.Equal(t, sequence.TempoMap{{Position: 480, Tempo: 60}}, seq.Tempos)
+	assert.Equal(t, sequence.TempoMap{{Position: 0, Tempo: 120}, {Position: 480, Tempo: 60}}, seq.TempoMap())
+}
+
+func TestFile_Sequence_PitchBend(t *testing.T) {
+	pb, err := ust.ParsePitchBend("", "", "-40;-20", "65,35", "0,5", "l")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tempo := 60.0
+	f := &ust.File{
+		Settings: ust.Settings{Tempo: 120},
+		Notes: []ust.Note{
+			{Length: 480, Lyric: "a", NoteNum: 60, Intensity: 100, PitchBend: pb},
+			{Length: 480, Lyric: "R", NoteNum: 60, Intensity: 100, Tempo: &tempo},
+			{Length: 480, Lyric: "i", NoteNum: 62, Intensity: 100, PitchBend: pb},
+		},
+	}
+
+	seq := f.Sequence()
+
+	// milliseconds from the start of the note become ticks at the tempo of the note,
+	// and tenths of a semitone relative to the note become absolute cents
+	assert.Equal(t, sequence.Curve{
+		{X: -38, Y: 5800, Interp: sequence.CurveInterpolationLinear},
+		{X: 24, Y: 6000, Interp: sequence.CurveInterpolationSine},
+		{X: 57, Y: 6050},
+	}, seq.Notes[0].PitchBend)
+	assert.Equal(t, sequence.Curve{
+		{X: -19, Y: 6000, Interp: sequence.CurveInterpolationLinear},
+		{X: 12, Y: 6200, Interp: sequence.CurveInterpolationSine},
+		{X: 28, Y: 6250},
+	}, seq.Notes[1].PitchBend)
+}
@@ -17,7 +17,9 @@ type Note struct {
 	StartPoint   *float64   // StartPoint is the time where to begin sampling inside the audio file (in milliseconds).
 	Envelope     *Envelope  // Envelope is the volume envelope.
 	PitchBend    *PitchBend // PitchBend is the pitch bend data.
+	Vibrato      *Vibrato   // Vibrato is the Mode2 vibrato. If it's omitted, the note has no vibrato.
 	Flags        string     // Flags is a string of flags for passing to the resampler. These can be resampler-specific.
+	Tempo        *float64   // Tempo is the tempo change (in BPM) starting at this note. If it's omitted, the tempo stays the same.
 }
 
 // IsLyricRest checks whether the lyrics is a rest / pause (e.g. "-", "R").
@@ -70,9 +70,9 @@ func ParsePitchBendMode(s string) (PitchBendMode, error) {
 // PitchBend represents the pitch bend data.
 type PitchBend struct {
 	Type   int               // Type is the pitch bend type (0 = no bend, 5 = default).
-	Start  umath.XY[float64] // Start is the starting point in ticks (X-axis) and initial pitch offset (Y-axis in semitones).
-	Widths []float64         // Widths are the widths in ticks for each pitch segment.
-	Ys     []float64         // Ys are the pitch offsets in semitones for each segment.
+	Start  umath.XY[float64] // Start is the starting point in milliseconds from the start of the note (X-axis) and initial pitch offset (Y-axis in tenths of a semitone).
+	Widths []float64         // Widths are the widths in milliseconds for each pitch segment.
+	Ys     []float64         // Ys are the pitch offsets at the ends of the segments in tenths of a semitone.
 	Modes  []PitchBendMode   // Modes are the interpolation modes for each segment.
 }
 
@@ -0,0 +1,51 @@
+package ust
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vibrato represents the Mode2 vibrato of a note (the VBR key).
+type Vibrato struct {
+	Length  float64 // Length is the length of the vibrato (in % of the note length), measured from the end of the note.
+	Cycle   float64 // Cycle is the period of one cycle (in milliseconds).
+	Depth   float64 // Depth is the depth (in cents).
+	FadeIn  float64 // FadeIn is the fade-in length (in % of the vibrato length).
+	FadeOut float64 // FadeOut is the fade-out length (in % of the vibrato length).
+	Phase   float64 // Phase is the phase at the start of the vibrato (in % of a cycle).
+	Height  float64 // Height is the vertical offset (in % of the depth).
+}
+
+// ParseVibrato parses a string representing a [Vibrato] in an UST note.
+//
+// UTAU writes an eighth value that is unused; it's accepted and ignored.
+func ParseVibrato(s string) (*Vibrato, error) {
+	parts := strings.Split(s, ",")
+
+	if len(parts) < 7 {
+		return nil, fmt.Errorf("vibrato string must contain at least 7 values, got %d", len(parts))
+	}
+	if len(parts) > 8 {
+		return nil, fmt.Errorf("vibrato string must contain at most 8 values, got %d", len(parts))
+	}
+
+	var vals [7]float64
+	for i := range vals {
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vibrato value at %d: %w", i, err)
+		}
+		vals[i] = v
+	}
+
+	return &Vibrato{
+		Length:  vals[0],
+		Cycle:   vals[1],
+		Depth:   vals[2],
+		FadeIn:  vals[3],
+		FadeOut: vals[4],
+		Phase:   vals[5],
+		Height:  vals[6],
+	}, nil
+}
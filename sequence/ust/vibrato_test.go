@@ -0,0 +1,66 @@
+package ust_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/sequence/ust"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVibrato(t *testing.T) {
+	tests := []struct {
+		name            string
+		s               string
+		expectedVibrato *ust.Vibrato
+		expectErr       bool
+		errContains     string
+	}{
+		{
+			name:            "ValidBasic",
+			s:               "65,180,35,20,20,0,0,0",
+			expectedVibrato: &ust.Vibrato{Length: 65, Cycle: 180, Depth: 35, FadeIn: 20, FadeOut: 20},
+			expectErr:       false,
+		},
+		{
+			name:            "Valid_SevenValues",
+			s:               "50,200,40,10,30,25,-10",
+			expectedVibrato: &ust.Vibrato{Length: 50, Cycle: 200, Depth: 40, FadeIn: 10, FadeOut: 30, Phase: 25, Height: -10},
+			expectErr:       false,
+		},
+		{
+			name:        "Invalid_NonNumericInput",
+			s:           "65,180,miku,20,20,0,0,0",
+			expectErr:   true,
+			errContains: "invalid vibrato value at 2",
+		},
+		{
+			name:        "Invalid_TooFewValues",
+			s:           "65,180,35",
+			expectErr:   true,
+			errContains: "must contain at least 7 values",
+		},
+		{
+			name:        "Invalid_TooManyValues",
+			s:           "65,180,35,20,20,0,0,0,0",
+			expectErr:   true,
+			errContains: "must contain at most 8 values",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vbr, err := ust.ParseVibrato(test.s)
+
+			if test.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, vbr)
+				if test.errContains != "" {
+					assert.Contains(t, err.Error(), test.errContains)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedVibrato, vbr)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package sequence
+
+import "math"
+
+// Vibrato represents the vibrato of a note in the UTAU (Mode2) form.
+//
+// The vibrato covers the end of the note. Its fades are relative to the length of the vibrato,
+// so a vibrato with FadeIn = 50 reaches its full depth halfway through.
+type Vibrato struct {
+	// Length is the length of the vibrato in percent of the note's duration, measured from the end of the note.
+	Length float64
+
+	// Period is the period of one cycle in milliseconds.
+	Period float64
+
+	// Depth is the depth (amplitude) in cents.
+	Depth float64
+
+	// FadeIn is the length of the fade-in in percent of the vibrato's length.
+	FadeIn float64
+
+	// FadeOut is the length of the fade-out in percent of the vibrato's length.
+	FadeOut float64
+
+	// Phase is the phase at the start of the vibrato in percent of a cycle.
+	Phase float64
+
+	// Height is the vertical offset of the oscillation in percent of the depth (-100 to 100).
+	Height float64
+}
+
+// At returns the pitch offset in cents at t milliseconds from the start of a note
+// that is duration milliseconds long. It's 0 outside of the vibrato.
+func (v Vibrato) At(t, duration float64) float64 {
+	length := duration * v.Length / 100
+	start := duration - length
+	if length <= 0 || v.Period <= 0 || t < start || t > duration {
+		return 0
+	}
+
+	t -= start
+	amp := v.Depth
+	if fadeIn := length * v.FadeIn / 100; t < fadeIn {
+		amp *= t / fadeIn
+	}
+	if fadeOut := length * v.FadeOut / 100; length-t < fadeOut {
+		amp *= (length - t) / fadeOut
+	}
+
+	phase := 2 * math.Pi * (t/v.Period + v.Phase/100)
+	return amp * (math.Sin(phase) + v.Height/100)
+}
@@ -0,0 +1,28 @@
+package sequence_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVibrato_At(t *testing.T) {
+	vbr := sequence.Vibrato{Length: 50, Period: 100, Depth: 100}
+
+	assert.InDelta(t, 0, vbr.At(400, 1000), 1e-9)
+	assert.InDelta(t, 0, vbr.At(500, 1000), 1e-9)
+	assert.InDelta(t, 100, vbr.At(525, 1000), 1e-9)
+	assert.InDelta(t, -100, vbr.At(575, 1000), 1e-9)
+	assert.InDelta(t, 0, vbr.At(1100, 1000), 1e-9)
+}
+
+func TestVibrato_At_FadesPhaseHeight(t *testing.T) {
+	vbr := sequence.Vibrato{Length: 50, Period: 100, Depth: 100, FadeIn: 20, FadeOut: 20, Phase: 25, Height: 10}
+
+	assert.InDelta(t, 0, vbr.At(500, 1000), 1e-9)   // start of the fade-in
+	assert.InDelta(t, -45, vbr.At(550, 1000), 1e-9) // halfway through the fade-in, at the trough
+	assert.InDelta(t, 110, vbr.At(700, 1000), 1e-9) // full depth
+	assert.InDelta(t, -45, vbr.At(950, 1000), 1e-9) // halfway through the fade-out, at the trough
+	assert.InDelta(t, 0, vbr.At(1000, 1000), 1e-9)  // end of the note
+}
@@ -0,0 +1,400 @@
+package gotau
+
+import (
+	"context"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+const (
+	// DefaultStreamMaxNoteLength is the longest a note can be held in a [Stream] by default.
+	DefaultStreamMaxNoteLength = 4 * time.Second
+
+	// DefaultStreamRelease is the fade-out time after a note-off in a [Stream] by default.
+	DefaultStreamRelease = 50 * time.Millisecond
+
+	// DefaultStreamLyric is the lyric that is sung by a [Stream] by default when no lyric is queued.
+	DefaultStreamLyric = "a"
+
+	streamTempo      = 120 // tempo for the resampler, since there's no song tempo in real time
+	streamResolution = 480
+	streamQueueSize  = 64 // number of notes that can wait for a worker
+)
+
+// Stream is a real-time [aio.SampleReader] that renders notes as they are played
+// (e.g. from a MIDI keyboard or a DAW bridge), instead of a whole song known up front.
+//
+// Notes are started and stopped with [Stream.NoteOn] and [Stream.NoteOff] (or [Stream.HandleMIDI]),
+// which are safe to call concurrently with ReadSamples. The output clock is driven by
+// ReadSamples, and every note is delayed by the latency of the Stream, which gives the resampler
+// time to render the note and lets the preutterance start before the note (as long as it fits
+// into the latency).
+//
+// ReadSamples never blocks on the resampler. If a note isn't resampled by the time it
+// should start playing (an underrun), it's silent until it's ready, and then it joins in
+// at the right position, so the timing of the following notes doesn't drift.
+//
+// Notes are resampled by a fixed number of background workers (the concurrency of the Synth,
+// see [Synth.SetConcurrency]), which run until the Stream is closed.
+type Stream struct {
+	synth *Synth
+
+	queue  chan *streamVoice // notes waiting for a worker
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu           sync.Mutex
+	pos          int // number of samples read so far
+	latency      int // in samples
+	release      int // in samples
+	maxLength    float64
+	voices       []*streamVoice
+	held         map[midi.Note]*streamVoice
+	prevLyric    string
+	lyrics       []string
+	defaultLyric string
+	closed       bool
+}
+
+// streamVoice is a single sounding note of a [Stream].
+type streamVoice struct {
+	job       *renderJob
+	ctx       context.Context
+	cancel    context.CancelFunc // cancels the resampling once the note can't be heard anymore
+	started   bool               // whether a worker has started resampling the note
+	start     int                // output position of the start of the sample
+	fadeIn    int                // in samples
+	gain      float32
+	releaseAt int // output position of the note-off; negative while the note is held
+}
+
+// NewStream creates a new [Stream] that renders notes with the voicebank, phonemizer,
+// resampler and caches of the Synth, with the given latency.
+//
+// The Synth is only used for its configuration and shouldn't be read from while the Stream
+// is in use. If its concurrency is greater than 1, its resampler and caches must be safe for
+// concurrent use. The Stream must be closed to stop its workers.
+func NewStream(synth *Synth, latency time.Duration) *Stream {
+	ctx, cancel := context.WithCancel(synth.ctx)
+	s := &Stream{
+		synth:        synth,
+		queue:        make(chan *streamVoice, streamQueueSize),
+		cancel:       cancel,
+		latency:      synth.msToSamples(float64(latency.Milliseconds())),
+		release:      synth.msToSamples(float64(DefaultStreamRelease.Milliseconds())),
+		maxLength:    float64(DefaultStreamMaxNoteLength.Milliseconds()),
+		held:         make(map[midi.Note]*streamVoice),
+		defaultLyric: DefaultStreamLyric,
+	}
+	for range synth.concurrency {
+		s.wg.Add(1)
+		go s.work(ctx)
+	}
+	return s
+}
+
+// Latency returns the latency of the Stream.
+func (s *Stream) Latency() time.Duration {
+	return time.Duration(s.latency) * time.Second / time.Duration(s.synth.sr)
+}
+
+// SetMaxNoteLength sets the longest time a note can be held. Longer notes end early.
+// Every note is resampled to this length, so longer values make the resampler slower.
+func (s *Stream) SetMaxNoteLength(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxLength = float64(d.Milliseconds())
+}
+
+// SetDefaultLyric sets the lyric that is sung when no lyric is queued by [Stream.QueueLyrics].
+func (s *Stream) SetDefaultLyric(lyric string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultLyric = lyric
+}
+
+// QueueLyrics queues lyrics for the notes started by [Stream.HandleMIDI].
+// Each note-on message takes the next lyric from the queue.
+func (s *Stream) QueueLyrics(lyrics ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lyrics = append(s.lyrics, lyrics...)
+}
+
+// NoteOn starts singing the lyric at the key. The velocity (0 to 127) controls the intensity.
+// If the key is already held, the previous note is stopped first.
+//
+// Like in a song, the previous lyric is used for resolving aliases (e.g. VCV) if another key
+// is still held, which makes legato playing sound connected.
+func (s *Stream) NoteOn(key midi.Note, velocity uint8, lyric string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noteOn(key, velocity, lyric)
+}
+
+// NoteOff stops singing the note at the key. It does nothing if the key isn't held.
+func (s *Stream) NoteOff(key midi.Note) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noteOff(key)
+}
+
+// HandleMIDI handles note-on and note-off MIDI messages and ignores the rest.
+// The lyrics are taken from [Stream.QueueLyrics], falling back to the default lyric.
+//
+// Its signature matches the callback of [midi.ListenTo], so a Stream can listen to a MIDI input port directly.
+// Messages take effect when they are received; the timestamp is ignored.
+func (s *Stream) HandleMIDI(msg midi.Message, timestampms int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var channel, key, velocity uint8
+	switch {
+	case msg.GetNoteStart(&channel, &key, &velocity):
+		lyric := s.defaultLyric
+		if len(s.lyrics) > 0 {
+			lyric = s.lyrics[0]
+			s.lyrics = s.lyrics[1:]
+		}
+		s.noteOn(midi.Note(key), velocity, lyric)
+	case msg.GetNoteEnd(&channel, &key):
+		s.noteOff(midi.Note(key))
+	}
+}
+
+// Held returns the keys that are currently held, in ascending order.
+func (s *Stream) Held() []midi.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]midi.Note, 0, len(s.held))
+	for key := range s.held {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Pending returns the number of played notes that haven't been resampled yet.
+// A pending note that should already be playing causes an underrun.
+func (s *Stream) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, v := range s.voices {
+		select {
+		case <-v.job.done:
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// Close stops the Stream and its workers, canceling the notes that are being resampled.
+// Subsequent ReadSamples calls return [io.EOF].
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// work resamples the queued notes until the queue is closed.
+func (s *Stream) work(ctx context.Context) {
+	defer s.wg.Done()
+	for v := range s.queue {
+		s.mu.Lock()
+		v.started = true
+		s.mu.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			v.job.err = err
+			close(v.job.done)
+			continue
+		}
+		s.synth.runJob(v.ctx, v.job)
+	}
+}
+
+func (s *Stream) noteOn(key midi.Note, velocity uint8, lyric string) {
+	s.noteOff(key)
+	if s.closed {
+		return
+	}
+
+	prevLyric := ""
+	if len(s.held) > 0 {
+		prevLyric = s.prevLyric
+	}
+	s.prevLyric = lyric
+
+	note := sequence.Note{
+		Lyric:     lyric,
+		Note:      key,
+		Intensity: float64(velocity) / 127,
+	}
+
+	otoEntry, ok := s.synth.getOtoEntry(prevLyric, note)
+	if !ok {
+		s.synth.logger.Warn("oto entry not found, ignoring note", "key", key, "lyric", lyric)
+		s.held[key] = nil // still held, so note-off works as usual
+		return
+	}
+	preutter, overlap := s.synth.getPreutterOverlap(otoEntry, note, nil)
+
+	length := preutter + s.maxLength
+	job := &renderJob{
+		note:     note,
+		otoEntry: otoEntry,
+		length:   s.synth.msToSamples(length),
+		done:     make(chan struct{}),
+	}
+	job.resampleCfg = s.synth.getResampleConfig(otoEntry, note, length)
+	job.resampleCfg.Tempo = streamTempo
+	job.resampleCfg.Resolution = streamResolution
+
+	if err := s.synth.beforeResample(job); err != nil {
+		s.synth.logger.Warn("failed to run hook, ignoring note", "key", key, "lyric", lyric, "error", err)
+		s.held[key] = nil
+		return
+	}
+
+	voice := &streamVoice{
+		job:   job,
+		start: s.pos + s.latency - s.synth.msToSamples(preutter),
+		// like the default envelope, fade in over at least a few milliseconds
+		fadeIn:    max(s.synth.msToSamples(max(overlap, sequence.DefaultEnvelope.P2)), 1),
+		gain:      float32(note.Intensity),
+		releaseAt: -1,
+	}
+	voice.ctx, voice.cancel = context.WithCancel(s.synth.ctx)
+
+	select {
+	case s.queue <- voice:
+	default:
+		voice.cancel()
+		s.synth.logger.Warn("too many notes waiting to be resampled, ignoring note", "key", key, "lyric", lyric)
+		s.held[key] = nil
+		return
+	}
+	s.voices = append(s.voices, voice)
+	s.held[key] = voice
+
+	s.synth.logger.Debug("note on", "key", key, "lyric", lyric, "alias", otoEntry.Alias)
+}
+
+func (s *Stream) noteOff(key midi.Note) {
+	voice, ok := s.held[key]
+	if !ok {
+		return
+	}
+	delete(s.held, key)
+	if voice == nil {
+		return
+	}
+	voice.releaseAt = s.pos + s.latency
+
+	// the length of the note is known now, so a note that's still waiting for a worker only has
+	// to be resampled up to the end of the release. A note that's already being resampled is
+	// canceled when its release ends, as its audio is needed until then.
+	if !voice.started {
+		length := s.synth.samplesToMs(voice.releaseAt + s.release - voice.start)
+		voice.job.length = min(voice.job.length, s.synth.msToSamples(length))
+		voice.job.resampleCfg.Length = min(voice.job.resampleCfg.Length, resampleLength(length))
+	}
+}
+
+func (s *Stream) ReadSamples(p []float32) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, io.EOF
+	}
+
+	clear(p)
+	s.voices = slices.DeleteFunc(s.voices, func(v *streamVoice) bool {
+		if !s.mix(v, p) {
+			return false
+		}
+		v.cancel()
+		return true
+	})
+	s.pos += len(p)
+	return len(p), nil
+}
+
+// mix adds the voice to p and reports whether the voice has finished.
+func (s *Stream) mix(v *streamVoice, p []float32) (finished bool) {
+	select {
+	case <-v.job.done:
+	default:
+		// underrun; stay silent until the note is ready, unless it has been released
+		// for so long that it can't be heard anymore
+		return v.releaseAt >= 0 && s.pos+len(p) >= v.releaseAt+s.release
+	}
+	if v.job.err != nil {
+		s.synth.logger.Warn("failed to render note", "key", v.job.note.Note, "lyric", v.job.note.Lyric, "error", v.job.err)
+		return true
+	}
+
+	samples := v.job.samples
+	for i := range p {
+		t := s.pos + i
+		j := t - v.start
+		if j < 0 {
+			continue
+		}
+		if j >= len(samples) {
+			return true
+		}
+
+		gain, ended := s.envelope(v, t, j, len(samples))
+		if ended {
+			return true
+		}
+		p[i] += samples[j] * v.gain * gain
+	}
+	return false
+}
+
+// envelope returns the gain of the voice at the output position t, which is j samples into its
+// sample of n samples, and whether the voice has ended. The voice fades in at the start, and
+// fades out during the release after the note-off or before the end of the sample (when the
+// note is held longer than the maximum length), so it never starts or stops abruptly.
+func (s *Stream) envelope(v *streamVoice, t, j, n int) (gain float32, ended bool) {
+	gain = 1
+	if j < v.fadeIn {
+		gain *= fade(float64(j) / float64(v.fadeIn))
+	}
+	if left := n - j; left < s.release {
+		gain *= fade(float64(left) / float64(s.release))
+	}
+	if v.releaseAt >= 0 && t >= v.releaseAt {
+		r := t - v.releaseAt
+		if r >= s.release {
+			return 0, true
+		}
+		gain *= fade(1 - float64(r)/float64(s.release))
+	}
+	return gain, false
+}
+
+// fade returns the gain of a raised-cosine fade-in at x from 0 to 1.
+func fade(x float64) float32 {
+	return float32((1 - math.Cos(math.Pi*x)) / 2)
+}
@@ -0,0 +1,210 @@
+package gotau_test
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers/testdrv"
+)
+
+func TestStream_HandleMIDI(t *testing.T) {
+	synth := gotau.New(1000, &voicebank.Voicebank{}, nopResampler{}, nil)
+	stream := gotau.NewStream(synth, 100*time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, stream.Latency())
+
+	drv := testdrv.New("gotau")
+	ins, err := drv.Ins()
+	assert.NoError(t, err)
+	outs, err := drv.Outs()
+	assert.NoError(t, err)
+
+	stop, err := midi.ListenTo(ins[0], stream.HandleMIDI)
+	assert.NoError(t, err)
+	defer stop()
+
+	send, err := midi.SendTo(outs[0])
+	assert.NoError(t, err)
+
+	assert.NoError(t, send(midi.NoteOn(0, 64, 100)))
+	assert.NoError(t, send(midi.NoteOn(0, 60, 100)))
+	assert.Equal(t, []midi.Note{60, 64}, stream.Held())
+
+	assert.NoError(t, send(midi.NoteOff(0, 64)))
+	assert.Equal(t, []midi.Note{60}, stream.Held())
+
+	// the lyrics aren't in the voicebank, so the stream stays silent without blocking
+	p := make([]float32, 100)
+	n, err := stream.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, make([]float32, 100), p)
+
+	assert.NoError(t, stream.Close())
+	_, err = stream.ReadSamples(p)
+	assert.Error(t, err)
+}
+
+// waitResampled waits until all played notes of the stream are resampled.
+func waitResampled(t *testing.T, stream *gotau.Stream) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); stream.Pending() > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("notes weren't resampled in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// readStream reads ms milliseconds of audio at 44.1 kHz from the stream.
+func readStream(t *testing.T, stream *gotau.Stream, ms int) []float32 {
+	t.Helper()
+	p := make([]float32, 441*ms/10)
+	n, err := stream.ReadSamples(p)
+	assert.NoError(t, err)
+	assert.Equal(t, len(p), n)
+	return p
+}
+
+// playStream plays the held keys (with lyrics) on a new stream and returns the first 500 ms.
+func playStream(t *testing.T, vb *voicebank.Voicebank, notes map[midi.Note]string) []float32 {
+	t.Helper()
+	stream := gotau.NewStream(gotau.New(44100, vb, loopResampler{}, nil), 50*time.Millisecond)
+	defer stream.Close()
+	for _, key := range []midi.Note{60, 64} {
+		if lyric, ok := notes[key]; ok {
+			stream.NoteOn(key, 127, lyric)
+		}
+	}
+	waitResampled(t, stream)
+	return readStream(t, stream, 500)
+}
+
+func TestStream_HeldNotes(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	a := playStream(t, vb, map[midi.Note]string{60: "a"})
+	i := playStream(t, vb, map[midi.Note]string{64: "i"})
+	both := playStream(t, vb, map[midi.Note]string{60: "a", 64: "i"})
+
+	assert.Greater(t, peak(a), 0.1)
+	assert.Greater(t, peak(i), 0.1)
+	want := make([]float32, len(both))
+	for j := range want {
+		want[j] = a[j] + i[j]
+	}
+	assert.InDeltaSlice(t, want, both, 1e-6)
+}
+
+// gatedResampler blocks until its gate is closed.
+type gatedResampler struct {
+	loopResampler
+	gate chan struct{}
+}
+
+func (r gatedResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	select {
+	case <-r.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.loopResampler.Resample(ctx, in, cfg)
+}
+
+func TestStream_Underrun(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	ref := gotau.NewStream(gotau.New(44100, vb, loopResampler{}, nil), 50*time.Millisecond)
+	defer ref.Close()
+	ref.NoteOn(60, 127, "a")
+	waitResampled(t, ref)
+	readStream(t, ref, 200)
+	want := readStream(t, ref, 200)
+
+	gate := make(chan struct{})
+	stream := gotau.NewStream(gotau.New(44100, vb, gatedResampler{gate: gate}, nil), 50*time.Millisecond)
+	defer stream.Close()
+	stream.NoteOn(60, 127, "a")
+
+	// the note isn't ready, so the stream stays silent without blocking
+	assert.Equal(t, make([]float32, 8820), readStream(t, stream, 200))
+	assert.Equal(t, 1, stream.Pending())
+
+	// once it's ready, it joins in where it would be without the underrun
+	close(gate)
+	waitResampled(t, stream)
+	assert.Equal(t, want, readStream(t, stream, 200))
+}
+
+// maxStep returns the largest difference between adjacent samples.
+func maxStep(samples []float32) float64 {
+	var m float64
+	for i := 1; i < len(samples); i++ {
+		m = max(m, math.Abs(float64(samples[i]-samples[i-1])))
+	}
+	return m
+}
+
+func TestStream_Envelope(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	// the steepest step of the 440 Hz sine at 44.1 kHz is about 0.031
+	const maxClick = 0.04
+
+	t.Run("release", func(t *testing.T) {
+		stream := gotau.NewStream(gotau.New(44100, vb, loopResampler{}, nil), 0)
+		defer stream.Close()
+		stream.NoteOn(60, 127, "a")
+		waitResampled(t, stream)
+
+		out := readStream(t, stream, 150)
+		stream.NoteOff(60)
+		out = append(out, readStream(t, stream, 200)...)
+		assert.Greater(t, peak(out), 0.1)
+		assert.Less(t, maxStep(out), maxClick)
+		assert.Zero(t, peak(out[len(out)-441:]))
+	})
+
+	t.Run("max note length", func(t *testing.T) {
+		stream := gotau.NewStream(gotau.New(44100, vb, loopResampler{}, nil), 0)
+		defer stream.Close()
+		stream.SetMaxNoteLength(150 * time.Millisecond)
+		stream.NoteOn(60, 127, "a")
+		waitResampled(t, stream)
+
+		// the note is held past the end of its sample, so it fades out on its own
+		out := readStream(t, stream, 400)
+		assert.Greater(t, peak(out), 0.1)
+		assert.Less(t, maxStep(out), maxClick)
+		assert.Zero(t, peak(out[len(out)-441:]))
+	})
+}
+
+func TestStream_Workers(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	res := &inFlightResampler{}
+	synth := gotau.New(44100, openTestVoicebank(t), res, nil)
+	synth.SetConcurrency(2)
+	stream := gotau.NewStream(synth, 0)
+	for key := midi.Note(60); key < 68; key++ {
+		stream.NoteOn(key, 127, "a")
+	}
+	waitResampled(t, stream)
+	assert.Equal(t, 2, res.max)
+
+	// closing the stream stops its workers
+	assert.NoError(t, stream.Close())
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "leaked goroutines")
+}
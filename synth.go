@@ -1,25 +1,28 @@
 package gotau
 
 import (
-	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/SladkyCitron/gotau/cache"
 	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/internal/timeutil"
 	"github.com/SladkyCitron/gotau/phonemizer"
+	"github.com/SladkyCitron/gotau/pitch"
 	"github.com/SladkyCitron/gotau/resample"
 	"github.com/SladkyCitron/gotau/sequence"
 	"github.com/SladkyCitron/gotau/voicebank"
 	"github.com/SladkyCitron/resona/afmt"
 	"github.com/SladkyCitron/resona/aio"
-	"github.com/SladkyCitron/resona/codec"
 	_ "github.com/SladkyCitron/resona/codec/au"
 	_ "github.com/SladkyCitron/resona/codec/qoa"
 	"github.com/SladkyCitron/resona/codec/wav"
@@ -30,30 +33,65 @@ const startBufSize = 4096 // Size of initial allocation for buffer
 
 // Synth is the main singing voice synthsizer that renders notes into audio samples.
 type Synth struct {
-	vb        *voicebank.Voicebank
-	ph        phonemizer.Phonemizer
-	res       resample.Resampler
-	cat       concat.Concatenator
-	resCache  cache.Cache
-	sched     *scheduler
-	sr        int
-	buf       []float32
-	vbFileBuf bytes.Buffer
-	prevLyric string
-	nextLyric string
+	vb          *voicebank.Voicebank
+	ph          phonemizer.Phonemizer
+	res         resample.Resampler
+	cat         concat.Concatenator
+	resCache    cache.Cache
+	sampleCache *SampleCache
+	sched       *scheduler
+	hooks       []Hook
+	autoPitch   *pitch.AutoPitch
+	autoVibrato *pitch.AutoVibrato
+	expressions map[string]sequence.Curve
+	breath      *Breath
+	sr          int
+	buf         []float32
+	prevLyric   string
+	nextLyric   string
+	prevNote    sequence.Note
+	prevOk      bool
+	phraseEnd   int // end tick of the notes planned so far, where a rest starts
+
+	concurrency int
+	pending     []*renderJob
+	outPos      int // number of samples concatenated so far
+	reserve     int // number of samples at the end of buf that may still change
+
+	startTick int // first tick of the render range
+	endTick   int // end tick of the render range; negative means until the end of the song
+	skip      int // number of samples left to drop before the start of the render range
+	remaining int // number of samples left until the end of the render range; negative means no limit
+
+	ctx        context.Context
+	logger     *slog.Logger
+	progressFn ProgressFunc
+	progress   Progress
+	startTime  time.Time
 }
 
 // New creates a new [Synth] with the given sample rate, voicebank, resampler, and concatenator.
+// If cat is nil, the built-in [concat.Wavtool] is used.
 func New(sr int, vb *voicebank.Voicebank, res resample.Resampler, cat concat.Concatenator) *Synth {
+	if cat == nil {
+		cat = &concat.Wavtool{}
+	}
 	s := &Synth{
-		vb:       vb,
-		ph:       &phonemizer.Default{},
-		res:      res,
-		cat:      cat,
-		resCache: &cache.NopCache{},
-		sched:    &scheduler{},
-		sr:       sr,
-		buf:      make([]float32, 0, startBufSize),
+		vb:          vb,
+		ph:          &phonemizer.Default{},
+		res:         res,
+		cat:         cat,
+		resCache:    &cache.NopCache{},
+		sampleCache: DefaultSampleCache,
+		sched:       &scheduler{},
+		sr:          sr,
+		buf:         make([]float32, 0, startBufSize),
+
+		concurrency: 1,
+		endTick:     -1,
+		remaining:   -1,
+		ctx:         context.Background(),
+		logger:      slog.New(slog.DiscardHandler),
 	}
 	return s
 }
@@ -75,6 +113,26 @@ func (s *Synth) SetResamplerCache(c cache.Cache) {
 	s.resCache = c
 }
 
+// SetSampleCache sets the cache for decoded voicebank samples.
+// It can be shared between multiple Synths. If c is nil, samples are decoded for every note.
+//
+// By default, [DefaultSampleCache] is used.
+func (s *Synth) SetSampleCache(c *SampleCache) {
+	s.sampleCache = c
+}
+
+// SetAutoPitch sets the generator of pitch curves for notes without pitch bend data.
+// If ap is nil (the default), such notes are rendered with a flat pitch.
+func (s *Synth) SetAutoPitch(ap *pitch.AutoPitch) {
+	s.autoPitch = ap
+}
+
+// SetAutoVibrato sets the generator of vibrato for notes without vibrato data.
+// If av is nil (the default), such notes have no vibrato.
+func (s *Synth) SetAutoVibrato(av *pitch.AutoVibrato) {
+	s.autoVibrato = av
+}
+
 // SetResolution sets the timing resolution in ticks per quarter note (TPQN).
 //
 // Higher values increase timing precision but may result in more scheduling
@@ -83,9 +141,14 @@ func (s *Synth) SetResolution(resolution int) {
 	s.sched.tpqn = resolution
 }
 
-// SetTempo sets the playback tempo in beats per minute (BPM).
+// SetTempo sets a constant playback tempo in beats per minute (BPM).
 func (s *Synth) SetTempo(tempo float64) {
-	s.sched.bpm = tempo
+	s.sched.tempos = sequence.TempoMap{{Position: 0, Tempo: tempo}}
+}
+
+// SetTempoMap sets the tempo map used for playback.
+func (s *Synth) SetTempoMap(m sequence.TempoMap) {
+	s.sched.tempos = m
 }
 
 // Enqueue adds notes to the synthesis queue.
@@ -94,161 +157,339 @@ func (s *Synth) SetTempo(tempo float64) {
 // in order during subsequent ReadSamples calls.
 func (s *Synth) Enqueue(notes ...sequence.Note) {
 	s.sched.enqueue(notes...)
+	s.progress.NotesTotal += len(notes)
 }
 
 // EnqueueSequence adds all notes from the given sequence to the synthesis
 // queue and updates the synthesizer's timing parameters.
 //
-// The sequence's resolution and tempo override the current scheduler settings.
+// The sequence's resolution, tempo map and expressions override the current settings.
 func (s *Synth) EnqueueSequence(seq sequence.Sequence) {
 	s.SetResolution(seq.Metadata.Resolution)
-	s.SetTempo(seq.Metadata.Tempo)
+	s.SetTempoMap(seq.TempoMap())
+	s.SetExpressions(seq.Expressions)
 	s.Enqueue(seq.Notes...)
 }
 
+// SetConcurrency sets the maximum number of notes that are resampled in parallel.
+//
+// The Synth looks ahead up to n notes in the queue and resamples them concurrently,
+// while concatenation still happens in order. The output is identical regardless
+// of the concurrency. Values less than 1 are treated as 1 (serial rendering).
+//
+// When n is greater than 1, the resampler and the resampler cache must be safe for concurrent use.
+func (s *Synth) SetConcurrency(n int) {
+	s.concurrency = max(n, 1)
+}
+
+// SetContext sets the context used for rendering.
+//
+// When the context is canceled or its deadline is exceeded, the cache, resampler
+// and concatenator operations in progress are aborted and ReadSamples returns
+// the context's error.
+func (s *Synth) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetLogger sets the logger for diagnostic messages about the rendered notes.
+// If l is nil, nothing is logged, which is the default.
+func (s *Synth) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	s.logger = l
+}
+
+// SetProgressFunc sets the function that is called with the rendering progress
+// after each rendered note. It is called on the goroutine that calls ReadSamples.
+func (s *Synth) SetProgressFunc(fn ProgressFunc) {
+	s.progressFn = fn
+}
+
+// Seek moves the playback position to the tick, keeping the end of the render range.
+// See [Synth.SetRange] for details.
+func (s *Synth) Seek(tick int) {
+	s.SetRange(tick, s.endTick)
+}
+
+// SeekTime moves the playback position to the time, keeping the end of the render range.
+// The time is converted to ticks using the current tempo map and resolution.
+func (s *Synth) SeekTime(t time.Duration) {
+	s.Seek(s.sched.secondsToTicks(t.Seconds()))
+}
+
+// SetRange sets the range of ticks to render to [start, end).
+// A negative end renders until the end of the song.
+//
+// The Synth is rewound and the output starts exactly at the start tick. Notes before the
+// range are not rendered, but still provide the lyric and timing context, so the output
+// is the same as the corresponding part of a full render (including the preutterance of
+// a note that starts before the start tick). The output ends at the end tick, or earlier
+// if the song ends before it.
+//
+// Notes that are being resampled in the background are discarded, so the resampler
+// cache is the only work that carries over.
+func (s *Synth) SetRange(start, end int) {
+	s.startTick = max(start, 0)
+	s.endTick = end
+	s.rewind()
+}
+
+// rewind resets the rendering state, so the next ReadSamples call starts at the start of the render range.
+func (s *Synth) rewind() {
+	s.sched.rewind()
+	clear(s.pending)
+	s.pending = s.pending[:0]
+	s.buf = s.buf[:0]
+	s.outPos = 0
+	s.reserve = 0
+	s.prevLyric = ""
+	s.nextLyric = ""
+	s.prevNote = sequence.Note{}
+	s.prevOk = false
+	s.phraseEnd = 0
+
+	s.progress = Progress{NotesTotal: s.progress.NotesTotal}
+	s.startTime = time.Time{}
+}
+
+// begin prepares the render range at the start of rendering.
+func (s *Synth) begin() {
+	s.startTime = time.Now()
+
+	s.skip = s.msToSamples(s.ticksToMs(s.startTick))
+	s.remaining = -1
+	if s.endTick >= 0 {
+		s.remaining = max(s.msToSamples(s.ticksToMs(s.endTick))-s.skip, 0)
+	}
+}
+
+// inRange reports whether a note sample from startMs to endMs is audible in the render range.
+func (s *Synth) inRange(startMs, endMs float64) bool {
+	if endMs <= s.ticksToMs(s.startTick) {
+		return false
+	}
+	return s.endTick < 0 || startMs < s.ticksToMs(s.endTick)
+}
+
+// Format returns the audio format of the rendered samples.
+// Voicebank samples in other formats are converted to it before resampling.
+func (s *Synth) Format() afmt.Format {
+	return afmt.Format{SampleRate: freq.Frequency(s.sr) * freq.Hertz, NumChannels: 1}
+}
+
+// Progress returns the current rendering progress.
+func (s *Synth) Progress() Progress {
+	p := s.progress
+	if !s.startTime.IsZero() {
+		p.Elapsed = time.Since(s.startTime)
+	}
+	return p
+}
+
 func (s *Synth) ReadSamples(p []float32) (int, error) {
-	n := 0
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
 
-	// drain the buffer
-	for n < len(p) && len(s.buf) > 0 {
-		copied := copy(p[n:], s.buf)
-		s.buf = s.buf[copied:]
-		n += copied
+	if s.startTime.IsZero() {
+		s.begin()
 	}
 
+	n := s.drain(p)
+
 	// fill the buffer
-	for n < len(p) {
-		if len(s.sched.queue) == 0 {
+	for n < len(p) && s.remaining != 0 {
+		s.fillPending()
+		if len(s.pending) == 0 {
+			// nothing left to concatenate into the tail
+			s.reserve = 0
+			n += s.drain(p[n:])
 			if n == 0 {
 				return 0, io.EOF
 			}
 			return n, nil
 		}
 
-		seconds := float64(len(p)-n) / float64(s.sr)
-		for note := range s.sched.popSeq(seconds) {
-			if err := s.renderNote(note); err != nil {
-				copied := copy(p[n:], s.buf)
-				s.buf = s.buf[copied:]
-				n += copied
-				return n, fmt.Errorf("gotau Synth: failed to render note %q: %w", note.Lyric, err)
+		job := s.pending[0]
+		select {
+		case <-job.done:
+		case <-s.ctx.Done():
+			return n, s.ctx.Err()
+		}
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+
+		if err := s.concatenate(job); err != nil {
+			n += s.drain(p[n:])
+			if ctxErr := s.ctx.Err(); ctxErr != nil {
+				return n, ctxErr
 			}
+			return n, fmt.Errorf("gotau Synth: failed to render note %q: %w", job.note.Lyric, err)
 		}
+		s.reportProgress(job)
 
-		copied := copy(p[n:], s.buf)
-		s.buf = s.buf[copied:]
-		n += copied
+		// keep the part of the tail that the next note overlaps
+		s.fillPending()
+		s.updateReserve()
+
+		n += s.drain(p[n:])
+	}
+	if n == 0 && s.remaining == 0 {
+		return 0, io.EOF
 	}
 	return n, nil
 }
 
-func (s *Synth) renderNote(note sequence.Note) error {
+// planNote computes the timing, resampler configuration and concatenator configuration of the note.
+// It must be called in order, as it advances the Synth's timing and lyric context.
+func (s *Synth) planNote(note sequence.Note) *renderJob {
+	job := &renderJob{note: note, done: make(chan struct{})}
+
 	// get next lyric
-	if next, ok := s.sched.peek(); ok {
+	next, hasNext := s.sched.peek()
+	if hasNext {
 		s.nextLyric = next.Lyric
 	} else {
 		s.nextLyric = ""
 	}
 
 	// get oto
-	otoEntry, otoOk := s.getOtoEntry(note)
-
-	// get preutterance of current and next note
-	preutter := s.getPreutter(otoEntry, note)
-	preutterSec := preutter / 1000
-	var nextPreutter float64
-	if next, ok := s.sched.peek(); ok {
-		if next.Preutterance != nil {
-			nextPreutter = *next.Preutterance
-		}
-		if nextOtoEntry, ok := s.getOtoEntry(next); ok {
-			nextPreutter = s.getPreutter(nextOtoEntry, next)
-		}
+	otoEntry, otoOk := s.getOtoEntry(s.prevLyric, note)
+
+	// the previous note only matters if it was sung and ends right where this one starts
+	var prev *sequence.Note
+	if s.prevOk && s.prevNote.Position+s.prevNote.Duration == note.Position {
+		prev = &s.prevNote
 	}
-	nextPreutterSec := nextPreutter / 1000
 
-	// emit possible silence before note
-	if startTick := note.Position - s.sched.secondsToTicks(preutterSec); startTick > s.sched.tickPos {
-		s.debugLog("silence", note)
-		buf := make([]float32, int((s.sched.ticksToSeconds(note.Position-s.sched.tickPos)-preutterSec)*float64(s.sr)))
-		s.buf = append(s.buf, buf...)
-		s.sched.tickPos = startTick
+	// get preutterance and overlap of current note
+	preutter, overlap := s.getPreutterOverlap(otoEntry, note, prev)
+	job.otoEntry = otoEntry
+	job.preutter = preutter
+	job.overlap = overlap
+
+	// the note's sample starts preutterance before the note and ends where the next
+	// note's sample starts (plus its overlap), or at the end of the note if there's a rest
+	startMs := s.ticksToMs(note.Position) - preutter
+	endMs := s.ticksToMs(note.Position + note.Duration)
+	if hasNext && next.Position == note.Position+note.Duration {
+		if nextOtoEntry, ok := s.getOtoEntry(note.Lyric, next); ok {
+			var nextPrev *sequence.Note
+			if otoOk {
+				nextPrev = &note
+			}
+			nextPreutter, nextOverlap := s.getPreutterOverlap(nextOtoEntry, next, nextPrev)
+			endMs = s.ticksToMs(next.Position) - nextPreutter + nextOverlap
+		}
 	}
 
-	s.debugLog("note", note)
+	s.prevNote = note
+	s.prevOk = otoOk
+	s.prevLyric = note.Lyric
 
-	// oto entry not found; emit silence instead
-	if !otoOk {
-		s.debugLog("fallback silence", note)
-		buf := make([]float32, int((s.sched.ticksToSeconds(note.Duration)-nextPreutterSec)*float64(s.sr)))
-		s.buf = append(s.buf, buf...)
-		s.sched.tickPos += note.Duration
-		s.prevLyric = note.Lyric
-		return nil
+	// oto entry not found or the note is outside of the render range; emit silence instead
+	if !otoOk || !s.inRange(startMs, endMs) {
+		if !otoOk {
+			s.logger.Warn("oto entry not found, rendering silence", "tick", note.Position, "lyric", note.Lyric)
+		}
+		job.start = s.msToSamples(endMs)
+		job.silent = true
+		close(job.done)
+		return job
 	}
 
-	// adjust actual note duration and timing
-	var enroachment float64
-	if _, ok := s.sched.peek(); ok && s.sched.ticksToSeconds(note.Duration) <= nextPreutterSec {
-		enroachment = nextPreutterSec - s.sched.ticksToSeconds(note.Duration)
+	offset := s.getStartPoint(note)
+	if startMs < 0 {
+		// there's no room before the start of the song; cut off the beginning
+		offset -= startMs
+		startMs = 0
+		overlap = 0
 	}
-	newLength := s.sched.ticksToSeconds(note.Duration) + preutterSec - enroachment
+	length := max(endMs-startMs, 0)
 
-	buf := make([]float32, int(newLength*float64(s.sr)))
+	job.start = s.msToSamples(startMs)
+	job.length = s.msToSamples(offset + length)
 
-	f, err := s.vb.FS().Open(otoEntry.FilePath())
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+	job.resampleCfg = s.getResampleConfig(otoEntry, note, offset+length)
 
-	s.vbFileBuf.Reset()
-	if _, err := io.Copy(&s.vbFileBuf, f); err != nil {
-		return err
+	// the resampled sample starts lead milliseconds before the note
+	lead := s.ticksToMs(note.Position) - startMs + offset
+	cfg := &job.resampleCfg
+	if len(note.PitchBend) > 0 {
+		cfg.PitchBend = s.getPitchBend(note, lead, cfg.Length, cfg.Tempo, cfg.Resolution)
+	} else if s.autoPitch != nil {
+		cfg.PitchBend = s.autoPitch.Generate(prev, note, lead, cfg.Length, cfg.Tempo, cfg.Resolution)
 	}
-
-	deco, _, err := codec.Decode(bytes.NewReader(s.vbFileBuf.Bytes()))
-	if err != nil {
-		return err
+	duration := s.ticksToMs(note.Position+note.Duration) - s.ticksToMs(note.Position)
+	vibrato := note.Vibrato
+	if vibrato == nil && s.autoVibrato != nil {
+		vibrato = s.autoVibrato.Vibrato(note, duration)
 	}
-	if sr := int(deco.Format().SampleRate.Hertz()); sr != s.sr {
-		return fmt.Errorf("voicebank (%d Hz) and synth (%d Hz) sample rate do not match", sr, s.sr)
+	if vibrato != nil {
+		cfg.PitchBend = pitch.ApplyVibrato(cfg.PitchBend, *vibrato, note.Note, lead, duration, cfg.Length, cfg.Tempo, cfg.Resolution)
 	}
 
-	newLength = math.Ceil((newLength+s.getStartPoint(note)+25)/50) * 5000
-	resampleCfg := resample.ResampleConfig{
-		Pitch:       note.Note,
-		Velocity:    s.getVelocity(note),
-		Flags:       note.Flags,
-		Offset:      otoEntry.Offset,
-		Length:      newLength,
-		Consonant:   otoEntry.Consonant,
-		Cutoff:      otoEntry.Cutoff,
+	job.concatCfg = concat.Config{
+		Offset:      offset,
+		Length:      length,
+		Envelope:    s.getEnvelope(note),
+		Overlap:     overlap,
 		Intensity:   note.Intensity,
-		Modulation:  note.Modulation,
-		Tempo:       s.sched.bpm,
-		Resolution:  s.sched.tpqn,
-		PitchBend:   note.PitchBend,
-		AudioFormat: afmt.Format{SampleRate: freq.Frequency(s.sr) * freq.Hertz, NumChannels: 1},
+		AudioFormat: job.resampleCfg.AudioFormat,
+	}
+
+	s.logger.Debug(
+		"planned note",
+		"tick", note.Position,
+		"lyric", note.Lyric,
+		"alias", otoEntry.Alias,
+		"preutterance", preutter,
+		"overlap", overlap,
+		"length", length,
+	)
+
+	if err := s.beforeResample(job); err != nil {
+		job.err = fmt.Errorf("failed to run hook: %w", err)
+		close(job.done)
+		return job
 	}
+	job.length = s.msToSamples(job.concatCfg.Offset + job.concatCfg.Length)
+
+	return job
+}
+
+// resampleNote loads the voicebank sample of the planned note and resamples it
+// (or loads it from the cache). It is safe to call concurrently with other jobs.
+func (s *Synth) resampleNote(ctx context.Context, job *renderJob) error {
+	resampleCfg := job.resampleCfg
+	started := time.Now()
+
+	decoded, err := s.loadSample(job.otoEntry.FilePath(), resampleCfg.AudioFormat)
+	if err != nil {
+		return err
+	}
+	sample := dsp.NewSliceReader(decoded.samples)
 
 	var resampled aio.SampleReader
-	key := s.getKeyFunc(resampleCfg)
-	ctx := context.Background()
+	key := s.getKeyFunc(decoded.hash, resampleCfg)
 	if rc, err := s.resCache.Open(ctx, key); err == nil {
-		resampled, err = wav.NewDecoder(rc)
+		samples, err := readCached(rc)
 		if err != nil {
 			return err
 		}
+		resampled = dsp.NewSliceReader(samples)
+		job.cacheHit = true
 	} else {
-		if analyzer, ok := s.res.(resample.Analyzer); ok {
+		// the analysis sidecar file describes the original sample, so it can't be used for a converted one
+		if analyzer, ok := s.res.(resample.Analyzer); ok && !decoded.converted {
 			// check if there's the analysis sidecar file available
-			ext := filepath.Ext(otoEntry.FilePath())
-			name := otoEntry.FilePath()[:len(otoEntry.FilePath())-len(ext)]
+			ext := filepath.Ext(job.otoEntry.FilePath())
+			name := job.otoEntry.FilePath()[:len(job.otoEntry.FilePath())-len(ext)]
 			analysisPath := name + strings.ReplaceAll(ext, ".", "_") + analyzer.AnalysisExt()
 			analysisFile, err := s.vb.FS().Open(analysisPath)
 			if err == nil {
-				resampled, err = analyzer.ResampleWithAnalysis(deco, analysisFile, resampleCfg)
+				resampled, err = analyzer.ResampleWithAnalysis(ctx, sample, analysisFile, resampleCfg)
 				if err != nil {
 					return fmt.Errorf("failed to resample: %w", err)
 				}
@@ -257,69 +498,116 @@ func (s *Synth) renderNote(note sequence.Note) error {
 				}
 			} else {
 				// nope
-				resampled, err = s.res.Resample(deco, resampleCfg)
+				resampled, err = s.res.Resample(ctx, sample, resampleCfg)
 				if err != nil {
 					return fmt.Errorf("failed to resample: %w", err)
 				}
 			}
 		} else {
-			resampled, err = s.res.Resample(deco, resampleCfg)
+			resampled, err = s.res.Resample(ctx, sample, resampleCfg)
 			if err != nil {
 				return fmt.Errorf("failed to resample: %w", err)
 			}
 		}
 
-		// cache the resampled audio
-		f, err := s.resCache.Create(ctx, key)
+		// buffer the resampled audio, so it can be both cached and concatenated
+		samples, err := dsp.ReadAll(resampled)
 		if err != nil {
-			_ = f.Abort()
-			return fmt.Errorf("failed to create cache entry: %w", err)
+			return fmt.Errorf("failed to read resampled audio: %w", err)
 		}
-
-		enc, err := wav.NewEncoder(
-			f,
-			resampleCfg.AudioFormat,
-			afmt.SampleFormat{BitDepth: 32, Encoding: afmt.SampleEncodingFloat, Endian: binary.LittleEndian},
-			wav.FormatFloat,
-		)
-		if err != nil {
-			_ = f.Abort()
-			return fmt.Errorf("failed to create wav encoder for caching: %w", err)
+		if err := s.cacheResampled(ctx, key, samples, resampleCfg.AudioFormat); err != nil {
+			return err
 		}
+		resampled = dsp.NewSliceReader(samples)
+	}
 
-		if _, err := aio.Copy(enc, resampled); err != nil {
-			_ = f.Abort()
-			return fmt.Errorf("failed to cache resampled audio: %w", err)
-		}
+	resampled, err = s.afterResample(ctx, job, resampled)
+	if err != nil {
+		return fmt.Errorf("failed to run hook: %w", err)
+	}
 
-		if err := enc.Close(); err != nil {
-			_ = f.Abort()
-			return fmt.Errorf("failed to close wav encoder for caching: %w", err)
-		}
+	buf := make([]float32, job.length)
+	n, err := readFull(resampled, buf)
+	if err != nil {
+		return fmt.Errorf("failed to read resampled audio: %w", err)
+	}
 
-		if err := f.Close(); err != nil {
-			_ = f.Abort()
-			return fmt.Errorf("failed to close cache entry: %w", err)
-		}
+	job.samples = buf[:n]
+
+	s.logger.Debug(
+		"resampled note",
+		"tick", job.note.Position,
+		"lyric", job.note.Lyric,
+		"alias", job.otoEntry.Alias,
+		"cache_hit", job.cacheHit,
+		"duration", time.Since(started),
+	)
+	return nil
+}
+
+// readCached reads the resampled audio of a cache entry and closes it.
+func readCached(rc io.ReadCloser) ([]float32, error) {
+	deco, err := wav.NewDecoder(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to decode cached audio: %w", err)
 	}
+	samples, err := dsp.ReadAll(deco)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to read cached audio: %w", err)
+	}
+	if err := rc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close cached audio: %w", err)
+	}
+	return samples, nil
+}
 
-	if _, err := resampled.ReadSamples(buf); err != nil && err != io.EOF {
-		return fmt.Errorf("failed to read resampled audio: %w", err)
+// cacheResampled stores the resampled audio in the resampler cache.
+func (s *Synth) cacheResampled(ctx context.Context, key cache.KeyFunc, samples []float32, format afmt.Format) error {
+	f, err := s.resCache.Create(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
 	}
 
-	s.buf = append(s.buf, buf...)
-	s.sched.tickPos += note.Duration
-	s.prevLyric = note.Lyric
+	enc, err := wav.NewEncoder(
+		f,
+		format,
+		afmt.SampleFormat{BitDepth: 32, Encoding: afmt.SampleEncodingFloat, Endian: binary.LittleEndian},
+		wav.FormatFloat,
+	)
+	if err != nil {
+		_ = f.Abort()
+		return fmt.Errorf("failed to create wav encoder for caching: %w", err)
+	}
+
+	if _, err := enc.WriteSamples(samples); err != nil {
+		_ = f.Abort()
+		return fmt.Errorf("failed to cache resampled audio: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		_ = f.Abort()
+		return fmt.Errorf("failed to close wav encoder for caching: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		_ = f.Abort()
+		return fmt.Errorf("failed to close cache entry: %w", err)
+	}
 	return nil
 }
 
-func (s *Synth) getOtoEntry(note sequence.Note) (e voicebank.OtoEntry, ok bool) {
-	resolveCfg := phonemizer.ResolveConfig{
-		PrevLyric: s.prevLyric,
+func (s *Synth) getResolveConfig(prevLyric string, note sequence.Note) phonemizer.ResolveConfig {
+	return phonemizer.ResolveConfig{
+		PrevLyric: prevLyric,
 		Lyric:     note.Lyric,
 		Note:      note.Note,
 	}
-	for alias := range s.ph.Resolve(resolveCfg) {
+}
+
+func (s *Synth) getOtoEntry(prevLyric string, note sequence.Note) (e voicebank.OtoEntry, ok bool) {
+	for alias := range s.ph.Resolve(s.getResolveConfig(prevLyric, note)) {
 		e, ok = s.vb.Oto.Get(alias)
 		if ok {
 			return e, true
@@ -328,11 +616,90 @@ func (s *Synth) getOtoEntry(note sequence.Note) (e voicebank.OtoEntry, ok bool)
 	return voicebank.OtoEntry{}, false
 }
 
-func (s *Synth) getPreutter(otoEntry voicebank.OtoEntry, note sequence.Note) float64 {
+// getPreutterOverlap returns the preutterance and overlap of the note in milliseconds.
+// Like in UTAU, they are scaled down when they don't fit into the first half of the previous note.
+func (s *Synth) getPreutterOverlap(otoEntry voicebank.OtoEntry, note sequence.Note, prev *sequence.Note) (preutter, overlap float64) {
+	preutter = otoEntry.Preutterance
 	if note.Preutterance != nil {
-		return *note.Preutterance
+		preutter = *note.Preutterance
 	}
-	return otoEntry.Preutterance
+	overlap = otoEntry.Overlap
+	if note.VoiceOverlap != nil {
+		overlap = *note.VoiceOverlap
+	}
+
+	if prev != nil {
+		limit := (s.ticksToMs(prev.Position+prev.Duration) - s.ticksToMs(prev.Position)) / 2
+		if diff := preutter - overlap; diff > limit {
+			rate := limit / diff
+			preutter *= rate
+			overlap *= rate
+		}
+	}
+	return preutter, overlap
+}
+
+// getPitchBend returns the pitch bend curve of the note in the form of [resample.ResampleConfig.PitchBend],
+// for a resampled sample that starts lead milliseconds before the note and is length milliseconds long.
+// The curve covers the whole sample, so the pitch before the first point and after the last one is defined.
+func (s *Synth) getPitchBend(note sequence.Note, lead, length float64, tempo float64, tpqn int) sequence.Curve {
+	toTicks := func(ms float64) int {
+		return timeutil.SecondsToTicks(ms/1000, tpqn, tempo)
+	}
+	noteMs := s.ticksToMs(note.Position)
+
+	curve := make(sequence.Curve, 0, len(note.PitchBend)+2)
+	for _, pt := range note.PitchBend {
+		x := toTicks(s.ticksToMs(note.Position+pt.X) - noteMs + lead)
+		if n := len(curve); n > 0 && curve[n-1].X >= x {
+			// too close to the previous point at this resolution; keep the later one
+			curve[n-1].Y = pt.Y
+			curve[n-1].Interp = pt.Interp
+			continue
+		}
+		curve = append(curve, sequence.CurvePoint{X: x, Y: pt.Y, Interp: pt.Interp})
+	}
+
+	if first := curve[0]; first.X > 0 {
+		curve = slices.Insert(curve, 0, sequence.CurvePoint{X: 0, Y: first.Y})
+	}
+	// cover the rounding at the end of the sample
+	if last := curve[len(curve)-1]; last.X <= toTicks(length) {
+		curve = append(curve, sequence.CurvePoint{X: toTicks(length) + 1, Y: last.Y})
+	}
+	return curve
+}
+
+// getResampleConfig returns the resampler configuration for rendering length milliseconds of the note's sample.
+func (s *Synth) getResampleConfig(otoEntry voicebank.OtoEntry, note sequence.Note, length float64) resample.ResampleConfig {
+	return resample.ResampleConfig{
+		Pitch:      note.Note,
+		Velocity:   s.getVelocity(note),
+		Flags:      s.getFlags(note),
+		Offset:     otoEntry.Offset,
+		Length:     resampleLength(length),
+		Consonant:  otoEntry.Consonant,
+		Cutoff:     otoEntry.Cutoff,
+		Intensity:  1, // applied by the concatenator, which also lets notes share cache entries
+		Modulation: note.Modulation,
+		Tempo:      s.sched.tempoAt(note.Position),
+		Resolution: s.sched.tpqn,
+
+		AudioFormat: s.Format(),
+	}
+}
+
+// resampleLength returns the length to request from the resampler for length milliseconds of audio.
+// It's rounded up to 50 ms with some headroom, like UTAU does.
+func resampleLength(length float64) float64 {
+	return math.Ceil((length+25)/50) * 50
+}
+
+func (s *Synth) getEnvelope(note sequence.Note) sequence.Envelope {
+	if note.Envelope != nil {
+		return *note.Envelope
+	}
+	return sequence.DefaultEnvelope
 }
 
 func (s *Synth) getVelocity(note sequence.Note) float64 {
@@ -349,6 +716,15 @@ func (s *Synth) getStartPoint(note sequence.Note) float64 {
 	return 0
 }
 
-func (s *Synth) debugLog(msg string, note sequence.Note) {
-	log.Printf("at %v -> %s: %v", s.sched.tickPos, msg, note)
+// ticksToMs converts the position in ticks to time in milliseconds.
+func (s *Synth) ticksToMs(tick int) float64 {
+	return s.sched.ticksToSeconds(tick) * 1000
+}
+
+func (s *Synth) msToSamples(ms float64) int {
+	return int(math.Round(ms * float64(s.sr) / 1000))
+}
+
+func (s *Synth) samplesToMs(samples int) float64 {
+	return float64(samples) * 1000 / float64(s.sr)
 }
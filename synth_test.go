@@ -0,0 +1,440 @@
+package gotau_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/cache"
+	"github.com/SladkyCitron/gotau/cache/memcache"
+	"github.com/SladkyCitron/gotau/concat"
+	"github.com/SladkyCitron/gotau/internal/dsp"
+	"github.com/SladkyCitron/gotau/resample"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/SladkyCitron/resona/aio"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// openTestVoicebank opens the voicebank in testdata/voicebank. Its aliases a and i
+// are 400 ms long sine waves at 440 Hz and 660 Hz (44.1 kHz, mono).
+func openTestVoicebank(t *testing.T) *voicebank.Voicebank {
+	t.Helper()
+	vb, err := voicebank.Open(os.DirFS("testdata/voicebank"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vb
+}
+
+// loopResampler renders notes by looping the input sample after the offset to the
+// requested length. It ignores the pitch, but is deterministic and safe for concurrent use.
+type loopResampler struct{}
+
+func (loopResampler) ID() string { return "loop" }
+
+func (loopResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var sample []float32
+	buf := make([]float32, 1024)
+	for {
+		n, err := in.ReadSamples(buf)
+		sample = append(sample, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sr := cfg.AudioFormat.SampleRate.Hertz()
+	sample = sample[min(int(cfg.Offset*sr/1000), len(sample)):]
+	out := make([]float32, int(cfg.Length*sr/1000))
+	if len(sample) > 0 {
+		for i := range out {
+			out[i] = sample[i%len(sample)]
+		}
+	}
+	return dsp.NewSliceReader(out), nil
+}
+
+func renderAll(t *testing.T, synth *gotau.Synth) []float32 {
+	t.Helper()
+	var out []float32
+	p := make([]float32, 1000)
+	for {
+		n, err := synth.ReadSamples(p)
+		out = append(out, p[:n]...)
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func peak(samples []float32) float64 {
+	var m float64
+	for _, v := range samples {
+		m = max(m, math.Abs(float64(v)))
+	}
+	return m
+}
+
+var testSeq = sequence.Sequence{
+	Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+	Notes: []sequence.Note{
+		{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+		{Position: 960, Duration: 480, Lyric: "i", Note: 62, Intensity: 1},
+		{Position: 1440, Duration: 960, Lyric: "a", Note: 64, Intensity: 1},
+	},
+}
+
+func TestSynth_ReadSamples(t *testing.T) {
+	synth := gotau.New(44100, openTestVoicebank(t), loopResampler{}, nil)
+	synth.EnqueueSequence(testSeq)
+
+	out := renderAll(t, synth)
+	assert.Len(t, out, 44100*2400/960) // 2400 ticks at 120 BPM
+	assert.Greater(t, peak(out), 0.4)  // the notes are sung, and crossfaded where they overlap
+	assert.Less(t, peak(out), 1.0)
+	assert.Zero(t, peak(out[:44100/4])) // the rest before the first note's preutterance
+}
+
+func TestSynth_AfterResample(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	plain := gotau.New(44100, vb, loopResampler{}, nil)
+	plain.EnqueueSequence(testSeq)
+	want := renderAll(t, plain)
+
+	synth := gotau.New(44100, vb, loopResampler{}, nil)
+	var seen int
+	synth.AddHook(gotau.Hook{
+		AfterResample: func(ctx context.Context, n *gotau.HookNote, resampled aio.SampleReader) (aio.SampleReader, error) {
+			// halve the volume
+			var samples []float32
+			p := make([]float32, 1024)
+			for {
+				nn, err := resampled.ReadSamples(p)
+				for _, v := range p[:nn] {
+					samples = append(samples, v/2)
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			seen += len(samples)
+			return dsp.NewSliceReader(samples), nil
+		},
+	})
+	synth.EnqueueSequence(testSeq)
+	got := renderAll(t, synth)
+
+	assert.NotZero(t, seen)
+	assert.Len(t, got, len(want))
+	assert.NotZero(t, peak(got))
+	for i := range want {
+		if !assert.InDelta(t, want[i]/2, got[i], 1e-6, "sample %d", i) {
+			break
+		}
+	}
+}
+
+func TestSynth_ResamplerCache(t *testing.T) {
+	vb := openTestVoicebank(t)
+	c := memcache.New()
+
+	first := gotau.New(44100, vb, loopResampler{}, nil)
+	first.SetResamplerCache(c)
+	first.EnqueueSequence(testSeq)
+	want := renderAll(t, first)
+	assert.Equal(t, 3, first.Progress().CacheMisses)
+	assert.NotZero(t, peak(want))
+
+	tc := &trackingCache{Cache: c}
+	second := gotau.New(44100, vb, loopResampler{}, nil)
+	second.SetResamplerCache(tc)
+	second.EnqueueSequence(testSeq)
+	got := renderAll(t, second)
+	assert.Equal(t, 3, second.Progress().CacheHits)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 3, tc.opened)
+	assert.Equal(t, 3, tc.closed) // the cached entries aren't left open
+}
+
+// trackingCache counts the entries opened and closed in a cache.
+type trackingCache struct {
+	cache.Cache
+
+	mu             sync.Mutex
+	opened, closed int
+}
+
+func (c *trackingCache) Open(ctx context.Context, key cache.KeyFunc) (io.ReadCloser, error) {
+	rc, err := c.Cache.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opened++
+	return &trackingReadCloser{ReadCloser: rc, c: c}, nil
+}
+
+type trackingReadCloser struct {
+	io.ReadCloser
+	c *trackingCache
+}
+
+func (r *trackingReadCloser) Close() error {
+	r.c.mu.Lock()
+	r.c.closed++
+	r.c.mu.Unlock()
+	return r.ReadCloser.Close()
+}
+
+// delayResampler is a loopResampler that takes longer for lower notes,
+// so concurrently resampled notes finish out of order.
+type delayResampler struct {
+	loopResampler
+}
+
+func (r delayResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	time.Sleep(time.Duration(72-cfg.Pitch) * time.Millisecond)
+	return r.loopResampler.Resample(ctx, in, cfg)
+}
+
+// recordingConcatenator records the length of each concatenated note.
+type recordingConcatenator struct {
+	concat.Wavtool
+	lengths []float64
+}
+
+func (c *recordingConcatenator) Concatenate(ctx context.Context, dst []float32, in []float32, cfg concat.Config) ([]float32, error) {
+	c.lengths = append(c.lengths, cfg.Length)
+	return c.Wavtool.Concatenate(ctx, dst, in, cfg)
+}
+
+// concurrencyTestSeq has notes of distinct lengths, which are adjacent, separated by rests or overlapping.
+func concurrencyTestSeq() sequence.Sequence {
+	seq := sequence.Sequence{Metadata: sequence.Metadata{Resolution: 480, Tempo: 150}}
+	pos := 240
+	for i := range 12 {
+		note := sequence.Note{Position: pos, Duration: 240 + 20*i, Lyric: "a", Note: midi.Note(60 + i%8), Intensity: 1}
+		if i%2 == 1 {
+			note.Lyric = "i"
+		}
+		seq.Notes = append(seq.Notes, note)
+		switch i % 4 {
+		case 1:
+			pos += note.Duration + 120 // rest
+		case 3:
+			pos += note.Duration - 60 // overlap
+		default:
+			pos += note.Duration
+		}
+	}
+	return seq
+}
+
+func TestSynth_SetConcurrency(t *testing.T) {
+	vb := openTestVoicebank(t)
+	seq := concurrencyTestSeq()
+
+	render := func(concurrency int) ([]float32, []float64) {
+		cat := &recordingConcatenator{}
+		synth := gotau.New(44100, vb, delayResampler{}, cat)
+		synth.SetConcurrency(concurrency)
+		synth.EnqueueSequence(seq)
+		return renderAll(t, synth), cat.lengths
+	}
+
+	want, wantOrder := render(1)
+	assert.NotZero(t, peak(want))
+	for _, concurrency := range []int{2, 4, 16} {
+		got, order := render(concurrency)
+		assert.Equal(t, want, got, "concurrency %d", concurrency)
+		assert.Equal(t, wantOrder, order, "concurrency %d", concurrency)
+	}
+}
+
+func TestSynth_SetConcurrency_Order(t *testing.T) {
+	seq := concurrencyTestSeq()
+	cat := &recordingConcatenator{}
+	synth := gotau.New(44100, openTestVoicebank(t), delayResampler{}, cat)
+	synth.SetConcurrency(4)
+
+	var want []float64
+	for _, note := range synth.Plan(seq).Notes {
+		want = append(want, note.ConcatConfig.Length)
+	}
+
+	synth.EnqueueSequence(seq)
+	renderAll(t, synth)
+	assert.Len(t, want, len(seq.Notes))
+	assert.Equal(t, want, cat.lengths) // concatenated in sequence order, although lower notes are resampled slower
+}
+
+// blockingResampler resamples the first note and blocks on the others until the context is canceled.
+type blockingResampler struct {
+	loopResampler
+}
+
+func (r blockingResampler) Resample(ctx context.Context, in aio.SampleReader, cfg resample.ResampleConfig) (aio.SampleReader, error) {
+	if cfg.Pitch != 60 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return r.loopResampler.Resample(ctx, in, cfg)
+}
+
+func TestSynth_SetContext_Cancel(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	synth := gotau.New(44100, openTestVoicebank(t), blockingResampler{}, nil)
+	synth.SetConcurrency(4)
+	synth.SetContext(ctx)
+	synth.SetProgressFunc(func(p gotau.Progress) {
+		if p.NotesRendered == 1 {
+			cancel() // cancel mid-render, while the other notes are being resampled
+		}
+	})
+	synth.EnqueueSequence(concurrencyTestSeq())
+
+	p := make([]float32, 44100)
+	var err error
+	for err == nil {
+		_, err = synth.ReadSamples(p)
+	}
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, synth.Progress().NotesRendered)
+
+	_, err = synth.ReadSamples(p)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the workers stop with the resampler
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "leaked goroutines")
+}
+
+func TestSynth_SetRange(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	full := gotau.New(44100, vb, loopResampler{}, nil)
+	full.EnqueueSequence(testSeq)
+	want := renderAll(t, full)
+
+	synth := gotau.New(44100, vb, loopResampler{}, nil)
+	synth.EnqueueSequence(testSeq)
+	synth.SetRange(720, 1920) // 750 ms to 2000 ms, starting in the middle of the first note's preutterance
+	got := renderAll(t, synth)
+	assert.Equal(t, want[33075:88200], got)
+
+	synth.SetRange(1920, 9600) // the end is after the end of the song
+	got = renderAll(t, synth)
+	assert.Equal(t, want[88200:], got)
+}
+
+func TestSynth_Seek(t *testing.T) {
+	vb := openTestVoicebank(t)
+
+	full := gotau.New(44100, vb, loopResampler{}, nil)
+	full.EnqueueSequence(testSeq)
+	want := renderAll(t, full)
+
+	synth := gotau.New(44100, vb, loopResampler{}, nil)
+	synth.EnqueueSequence(testSeq)
+	_, err := synth.ReadSamples(make([]float32, 60000))
+	assert.NoError(t, err)
+
+	synth.Seek(960) // backwards, 1000 ms
+	got := renderAll(t, synth)
+	assert.Equal(t, want[44100:], got)
+
+	synth.SeekTime(1250 * time.Millisecond) // 1200 ticks
+	got = renderAll(t, synth)
+	assert.Equal(t, want[55125:], got)
+
+	synth.Seek(0)
+	got = renderAll(t, synth)
+	assert.Equal(t, want, got)
+}
+
+func TestSynth_Seek_LongRest(t *testing.T) {
+	// 500 seconds of rest before the note, i.e. 88 MB of silence at 44.1 kHz
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 480 * 1000, Duration: 480, Lyric: "a", Note: 60, Intensity: 1},
+		},
+	}
+	synth := gotau.New(44100, openTestVoicebank(t), loopResampler{}, nil)
+	synth.EnqueueSequence(seq)
+	synth.Seek(480 * 999)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	out := renderAll(t, synth)
+	runtime.ReadMemStats(&after)
+
+	assert.Len(t, out, 44100)
+	assert.NotZero(t, peak(out))
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(16<<20), "the skipped silence must not be allocated")
+}
+
+func TestSynth_Envelope(t *testing.T) {
+	vb := openTestVoicebank(t)
+	render := func(env *sequence.Envelope) []float32 {
+		seq := sequence.Sequence{
+			Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+			Notes:    []sequence.Note{{Position: 480, Duration: 480, Lyric: "a", Note: 60, Intensity: 1, Envelope: env}},
+		}
+		synth := gotau.New(44100, vb, loopResampler{}, nil)
+		synth.EnqueueSequence(seq)
+		return renderAll(t, synth)
+	}
+
+	want := render(&sequence.DefaultEnvelope)
+	assert.Equal(t, want, render(nil)) // the default envelope is used when it's omitted
+
+	// half the volume after a 100 ms fade-in
+	got := render(&sequence.Envelope{
+		P1: 0, P2: 100, P3: 35, P4: 0, P5: 0,
+		V1: 0, V2: 50, V3: 50, V4: 0, V5: 50,
+	})
+	assert.Len(t, got, len(want))
+
+	// the sample starts 60 ms (the preutterance) before the note at 500 ms
+	start := 44100 * 440 / 1000
+	fadeIn := got[start : start+4410]
+	assert.Less(t, peak(fadeIn[:441]), 0.5*peak(want[start:start+441]))
+
+	middle := start + 8820 // 200 ms into the sample, until 100 ms before its end
+	assert.Greater(t, peak(want[middle:middle+4410]), 0.1)
+	for i := middle; i < start+44100*(60+500-100)/1000; i++ {
+		if !assert.InDelta(t, 0.5*want[i], got[i], 1e-4, "sample %d", i) {
+			break
+		}
+	}
+}
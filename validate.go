@@ -0,0 +1,122 @@
+package gotau
+
+import (
+	"slices"
+
+	"github.com/SladkyCitron/gotau/sequence"
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// The range of notes supported by UTAU (C1 to B7). Notes outside of it are reported by [Synth.Validate].
+const (
+	MinNote midi.Note = 24
+	MaxNote midi.Note = 107
+)
+
+// ValidationReport represents the result of [Synth.Validate].
+type ValidationReport struct {
+	// UnresolvedLyrics holds the notes whose lyric didn't resolve to any oto entry.
+	// These notes are rendered as silence.
+	UnresolvedLyrics []UnresolvedLyric
+
+	// ShortNotes holds the notes that are shorter than their preutterance.
+	ShortNotes []ShortNote
+
+	// OutOfRangeNotes holds the notes with a pitch outside of [MinNote] and [MaxNote].
+	OutOfRangeNotes []OutOfRangeNote
+}
+
+// UnresolvedLyric represents a note whose lyric didn't resolve to any oto entry.
+type UnresolvedLyric struct {
+	// Position is the position of the note in MIDI ticks.
+	Position int
+
+	// Lyric is the lyric of the note.
+	Lyric string
+
+	// Candidates is the list of aliases emitted by the phonemizer, in the order they were tried.
+	Candidates []string
+}
+
+// ShortNote represents a note that is shorter than its preutterance.
+type ShortNote struct {
+	// Position is the position of the note in MIDI ticks.
+	Position int
+
+	// Lyric is the lyric of the note.
+	Lyric string
+
+	// Alias is the resolved alias of the note.
+	Alias string
+
+	// Duration is the duration of the note in milliseconds.
+	Duration float64
+
+	// Preutterance is the preutterance of the note in milliseconds.
+	Preutterance float64
+}
+
+// OutOfRangeNote represents a note with a pitch outside of the supported range.
+type OutOfRangeNote struct {
+	// Position is the position of the note in MIDI ticks.
+	Position int
+
+	// Lyric is the lyric of the note.
+	Lyric string
+
+	// Note is the MIDI note number.
+	Note midi.Note
+}
+
+// OK reports whether the report doesn't contain any issues.
+func (r ValidationReport) OK() bool {
+	return len(r.UnresolvedLyrics) == 0 && len(r.ShortNotes) == 0 && len(r.OutOfRangeNotes) == 0
+}
+
+// Validate checks the sequence against the voicebank and the phonemizer of the Synth without rendering it.
+//
+// The lyrics are resolved exactly like during rendering (including the previous lyric
+// context). Validate doesn't modify the state of the Synth, so it can be called at any time.
+func (s *Synth) Validate(seq sequence.Sequence) ValidationReport {
+	var report ValidationReport
+
+	tempos := seq.TempoMap()
+	tpqn := seq.Metadata.Resolution
+	notes := slices.SortedStableFunc(slices.Values(seq.Notes), sortFn)
+
+	prevLyric := ""
+	for _, note := range notes {
+		if note.Note < MinNote || note.Note > MaxNote {
+			report.OutOfRangeNotes = append(report.OutOfRangeNotes, OutOfRangeNote{
+				Position: note.Position,
+				Lyric:    note.Lyric,
+				Note:     note.Note,
+			})
+		}
+
+		otoEntry, ok := s.getOtoEntry(prevLyric, note)
+		if !ok {
+			report.UnresolvedLyrics = append(report.UnresolvedLyrics, UnresolvedLyric{
+				Position:   note.Position,
+				Lyric:      note.Lyric,
+				Candidates: slices.Collect(s.ph.Resolve(s.getResolveConfig(prevLyric, note))),
+			})
+		} else {
+			preutter, _ := s.getPreutterOverlap(otoEntry, note, nil)
+			duration := (tempos.TicksToSeconds(note.Position+note.Duration, tpqn) - tempos.TicksToSeconds(note.Position, tpqn)) * 1000
+			if duration < preutter {
+				report.ShortNotes = append(report.ShortNotes, ShortNote{
+					Position:     note.Position,
+					Lyric:        note.Lyric,
+					Alias:        otoEntry.Alias,
+					Duration:     duration,
+					Preutterance: preutter,
+				})
+			}
+		}
+
+		prevLyric = note.Lyric
+	}
+
+	return report
+}
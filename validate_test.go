@@ -0,0 +1,37 @@
+package gotau_test
+
+import (
+	"testing"
+
+	"github.com/SladkyCitron/gotau"
+	"github.com/SladkyCitron/gotau/sequence"
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynth_Validate(t *testing.T) {
+	vb := &voicebank.Voicebank{
+		Oto: voicebank.Oto{
+			{Filename: "a.wav", Alias: "a", Preutterance: 100},
+			{Filename: "ka.wav", Alias: "ka", Preutterance: 300},
+		},
+	}
+	synth := gotau.New(44100, vb, nil, nil)
+
+	seq := sequence.Sequence{
+		Metadata: sequence.Metadata{Resolution: 480, Tempo: 120},
+		Notes: []sequence.Note{
+			{Position: 0, Duration: 480, Lyric: "a", Note: 60},
+			{Position: 480, Duration: 240, Lyric: "ka", Note: 60}, // 250 ms
+			{Position: 720, Duration: 480, Lyric: "xyz", Note: 60},
+			{Position: 1200, Duration: 480, Lyric: "a", Note: 12},
+		},
+	}
+
+	report := synth.Validate(seq)
+
+	assert.False(t, report.OK())
+	assert.Equal(t, []gotau.UnresolvedLyric{{Position: 720, Lyric: "xyz", Candidates: []string{"xyz"}}}, report.UnresolvedLyrics)
+	assert.Equal(t, []gotau.ShortNote{{Position: 480, Lyric: "ka", Alias: "ka", Duration: 250, Preutterance: 300}}, report.ShortNotes)
+	assert.Equal(t, []gotau.OutOfRangeNote{{Position: 1200, Lyric: "a", Note: 12}}, report.OutOfRangeNotes)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"image"
 	"io/fs"
+	"log/slog"
 	"path"
 	"strings"
 
@@ -181,6 +182,7 @@ type voicebankConfig struct {
 	fileEncoding encoding.Encoding
 	decodeAssets bool
 	strict       bool
+	logger       *slog.Logger
 }
 
 // using a "universal" Option type here instead of something like OpenOption just in case
@@ -212,12 +214,24 @@ func WithStrict(strict bool) Option {
 	}
 }
 
+// WithLogger specifies the logger for diagnostic messages (e.g. ignored non-fatal errors).
+// By default (or if logger is nil), nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *voicebankConfig) {
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		cfg.logger = logger
+	}
+}
+
 // Open opens an UTAU voicebank from the given filesystem.
 func Open(fsys fs.FS, opts ...Option) (*Voicebank, error) {
 	cfg := &voicebankConfig{
 		fileEncoding: encoding.Nop,
 		decodeAssets: true,
 		strict:       false,
+		logger:       slog.New(slog.DiscardHandler),
 	}
 
 	for _, opt := range opts {
@@ -298,6 +312,7 @@ func openNonInstaller(fsys fs.FS, cfg *voicebankConfig) (*Voicebank, error) {
 			if err != nil {
 				return fmt.Errorf("failed to parse oto.ini file: %w", err)
 			}
+			cfg.logger.Debug("loaded oto.ini", "path", path, "entries", len(oto))
 			vb.Oto = append(vb.Oto, oto...)
 		}
 		return nil
@@ -381,8 +396,11 @@ func parseCharacterInfo(fsys fs.FS, cfg *voicebankConfig) (*CharacterInfo, error
 				value = strings.ReplaceAll(value, "\\", "/")
 				info.Image.Path = value
 				if cfg.decodeAssets {
-					if err := info.Image.Decode(fsys); err != nil && cfg.strict {
-						return nil, fmt.Errorf("failed to decode character image: %w", err)
+					if err := info.Image.Decode(fsys); err != nil {
+						if cfg.strict {
+							return nil, fmt.Errorf("failed to decode character image: %w", err)
+						}
+						cfg.logger.Warn("failed to decode character image, skipping", "path", value, "error", err)
 					}
 				}
 			case "sample":
@@ -390,8 +408,11 @@ func parseCharacterInfo(fsys fs.FS, cfg *voicebankConfig) (*CharacterInfo, error
 				value = strings.ReplaceAll(value, "\\", "/")
 				info.Sample.Path = value
 				if cfg.decodeAssets {
-					if err := info.Sample.Decode(fsys); err != nil && cfg.strict {
-						return nil, fmt.Errorf("failed to decode character sample audio: %w", err)
+					if err := info.Sample.Decode(fsys); err != nil {
+						if cfg.strict {
+							return nil, fmt.Errorf("failed to decode character sample audio: %w", err)
+						}
+						cfg.logger.Warn("failed to decode character sample audio, skipping", "path", value, "error", err)
 					}
 				}
 			}
@@ -0,0 +1,40 @@
+package voicebank_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/SladkyCitron/gotau/voicebank"
+	"github.com/stretchr/testify/assert"
+)
+
+var loggerTestFS = fstest.MapFS{
+	"oto.ini":       {Data: []byte("a.wav=a,0,100,-50,60,20\n")},
+	"character.txt": {Data: []byte("name=Test\nimage=missing.png\n")},
+}
+
+func TestOpen_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	vb, err := voicebank.Open(loggerTestFS, voicebank.WithLogger(logger))
+	assert.NoError(t, err)
+	assert.Len(t, vb.Oto, 1)
+	assert.Contains(t, buf.String(), `level=WARN msg="failed to decode character image, skipping" path=missing.png`)
+	assert.Contains(t, buf.String(), `level=DEBUG msg="loaded oto.ini" path=oto.ini entries=1`)
+}
+
+func TestOpen_NoLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(defaultLogger)
+
+	_, err := voicebank.Open(loggerTestFS, voicebank.WithLogger(nil))
+	assert.NoError(t, err)
+	_, err = voicebank.Open(loggerTestFS)
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}